@@ -0,0 +1,86 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ageComparisons are the operators that age params can use, longest first so
+// that "<=" isn't taken for "<".
+var ageComparisons = []string{"<=", ">=", "!=", "<", ">", "="}
+
+// ageMatcher matches the codepoints by the Unicode version they were assigned
+// in, for params like age=6.0 (assigned in 6.0), age<=6.0 (assigned in 6.0 or
+// earlier), or age>13.0 (assigned after 13.0). Unassigned codepoints never
+// match. The age values that match are worked out up front, so that matching
+// is just a lookup.
+func ageMatcher(param string) (CodepointMatcher, bool) {
+	rest := strings.TrimPrefix(param, "age")
+	if rest == param {
+		return nil, false
+	}
+	comparison := ""
+	for _, c := range ageComparisons {
+		if strings.HasPrefix(rest, c) {
+			comparison = c
+			break
+		}
+	}
+	if comparison == "" {
+		return nil, false
+	}
+	version, err := parseUnicodeVersion(rest[len(comparison):])
+	if err != nil {
+		panic(fmt.Errorf("%v: %v", param, err))
+	}
+
+	requireAttributes(param, "age")
+	if current, err := parseUnicodeVersion(generatedUnicodeVersion); err == nil && version.After(current) {
+		reportWarning("%v: %v is newer than the embedded Unicode data (%v)", param, version, generatedUnicodeVersion)
+	}
+
+	matching := map[string]bool{}
+	for _, value := range attributeValues("age") {
+		age, err := parseUnicodeVersion(value)
+		if err != nil {
+			continue
+		}
+		switch comparison {
+		case "=":
+			matching[value] = age == version
+		case "!=":
+			matching[value] = age != version
+		case "<":
+			matching[value] = version.After(age)
+		case "<=":
+			matching[value] = !age.After(version)
+		case ">":
+			matching[value] = age.After(version)
+		case ">=":
+			matching[value] = !version.After(age)
+		}
+	}
+	return func(cp Codepoint) bool {
+		return matching[cp.Age]
+	}, true
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This file is the API for programs that use the finder as a library rather
+// than running the command line tool. Queries are written the same way as on
+// the command line, and results aren't cached on disk.
+
+// Query returns the ranges of codepoints that match a query (e.g. "cat=L ch=_"
+// or "(cat=L | cat=Nd) & !script=Han"). It's safe to call from multiple
+// goroutines at once.
+func Query(expr string) (Ranges, error) {
+	return QueryRange(expr, 0, maxCodepoint)
+}
+
+// QueryRange is Query, restricted to the codepoints from low to high
+// inclusive.
+func QueryRange(expr string, low rune, high rune) (ranges Ranges, err error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, fmt.Errorf("empty query")
+	}
+	if low < 0 || high > maxCodepoint || low > high {
+		return nil, fmt.Errorf("%U-%U: invalid codepoint range", low, high)
+	}
+	defer recoverError(&err)
+	return searchRanges(expr, low, high), nil
+}
+
+// Lookup returns the properties of a codepoint.
+func Lookup(cp rune) (codepoint Codepoint, err error) {
+	if cp < 0 || cp > maxCodepoint {
+		return codepoint, fmt.Errorf("%U: not a codepoint", cp)
+	}
+	defer recoverError(&err)
+	return getCodepoint(cp), nil
+}
+
+// DataVersion returns the version of the Unicode data that queries run
+// against, or "" if there isn't any (i.e. a build with the nodata tag that
+// hasn't loaded a data file yet).
+func DataVersion() string {
+	return generatedUnicodeVersion
+}
+
+// LoadDataFile replaces the built in Unicode data with the data file at path
+// (see -write-data). It has to be called before the first query.
+func LoadDataFile(path string) error {
+	return loadDataFile(path)
+}
+
+// recoverError turns a panic from the query machinery into an error. It must
+// be deferred.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		if e, ok := r.(error); ok {
+			*err = e
+		} else {
+			*err = fmt.Errorf("%v", r)
+		}
+	}
+}
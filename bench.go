@@ -0,0 +1,237 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime"
+	"sync"
+	"time"
+)
+
+func init() {
+	subcommands["bench"] = subcommand{
+		description: "Measure query, table decode, and output performance",
+		run:         runBench,
+	}
+}
+
+// Representative queries, from a single small category up to the printable
+// character set from the README.
+var benchQueries = []struct {
+	name  string
+	query string
+}{
+	{"ascii-letters", "ch=a-z ch=A-Z"},
+	{"category", "cat=Lu"},
+	{"major-category", "cat=L"},
+	{"printable", "cat=Cf cat=L cat=M cat=N cat=P cat=S cat=Z cp=09 cp=0a cp=0d"},
+}
+
+// benchmark is one measurement, which the bench subcommand runs itself and
+// bench_test.go runs with go test -bench. newOp is called once per goroutine
+// (parallel benchmarks have one per CPU) before timing starts, and returns a
+// func that does one iteration.
+type benchmark struct {
+	name     string
+	parallel bool
+	newOp    func() func()
+}
+
+// benchMatcher compiles a query the way a single command line run would
+// (nothing compiled yet).
+func benchMatcher(q string) *CompiledMatcher {
+	matchers := parseMatchers(q)
+	return compileMatcher(func(cp Codepoint) bool {
+		for _, matcher := range matchers {
+			if matcher(cp) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// benchmarks returns every benchmark. Names are grouped by what they measure,
+// e.g. Query/category.
+func benchmarks() (list []benchmark) {
+	add := func(name string, parallel bool, newOp func() func()) {
+		list = append(list, benchmark{name: name, parallel: parallel, newOp: newOp})
+	}
+
+	add("DecodeTable", false, func() func() {
+		return func() {
+			for plane := 0; plane < planeCount; plane++ {
+				decodePlane(plane)
+			}
+		}
+	})
+	for _, q := range benchQueries {
+		q := q
+		add("Query/"+q.name, false, func() func() {
+			return func() { query(0, maxCodepoint, benchMatcher(q.query).Matches, nil) }
+		})
+		// Over a narrow -range window.
+		add("WindowQuery/"+q.name, false, func() func() {
+			return func() {
+				scanQuery(benchMatcher(q.query), planScan(0x20, 0x24f, nil), func(r Range) error { return nil })
+			}
+		})
+		add("CachedQuery/"+q.name, false, func() func() {
+			return func() { searchRanges(q.query, 0, maxCodepoint) }
+		})
+	}
+
+	// The parallel benchmarks share the decoded table and compiled queries
+	// between goroutines, the way a server would.
+	for _, q := range benchQueries {
+		q := q
+		add("ParallelQuery/"+q.name, true, func() func() {
+			var buffer Ranges
+			return func() { buffer = searchRangesInto(buffer, q.query, 0, maxCodepoint) }
+		})
+	}
+	add("ParallelLookup", true, func() func() {
+		cp := rune(0)
+		return func() {
+			getCodepoint(cp)
+			attributeValue("nv", cp)
+			cp = (cp + 0x101) % (maxCodepoint + 1)
+		}
+	})
+
+	// Every output format, writing the biggest of the queries.
+	for _, format := range outputFormatNames() {
+		format := format
+		add("Emit/"+format, false, func() func() {
+			ranges := searchRanges(benchQueries[len(benchQueries)-1].query, 0, maxCodepoint)
+			opts := &OutputOptions{Leadup: "    char ::= ", HighCol: 80, AllowNonstandardUTF8: true}
+			return func() {
+				if err := emitRanges(io.Discard, format, ranges, opts); err != nil {
+					panic(fmt.Errorf("%v: %w", format, err))
+				}
+			}
+		})
+	}
+	return
+}
+
+// benchResult is what measureBenchmark found out about a benchmark.
+type benchResult struct {
+	n      int
+	time   time.Duration
+	allocs uint64
+	bytes  uint64
+}
+
+func (_this benchResult) String() string {
+	n := uint64(_this.n)
+	return fmt.Sprintf("%10d %14.1f ns/op %10d B/op %8d allocs/op",
+		_this.n, float64(_this.time.Nanoseconds())/float64(n), _this.bytes/n, _this.allocs/n)
+}
+
+// measureBenchmark runs a benchmark for at least benchTime, raising the
+// number of iterations the way go test -bench does.
+func measureBenchmark(bm benchmark, benchTime time.Duration) (result benchResult) {
+	for n := 1; ; {
+		result = runBenchmarkN(bm, n)
+		if result.time >= benchTime || n >= 1e9 {
+			return
+		}
+		// Aim a little past benchTime, but don't grow too fast.
+		next := int(1.2 * float64(n) * float64(benchTime) / float64(result.time+1))
+		if next > 100*n {
+			next = 100 * n
+		}
+		if next <= n {
+			next = n + 1
+		}
+		n = next
+	}
+}
+
+// runBenchmarkN runs n iterations of a benchmark, split between the
+// goroutines of a parallel one.
+func runBenchmarkN(bm benchmark, n int) benchResult {
+	goroutines := 1
+	if bm.parallel {
+		goroutines = runtime.GOMAXPROCS(0)
+	}
+	ops := make([]func(), goroutines)
+	for i := range ops {
+		ops[i] = bm.newOp()
+	}
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i, op := range ops {
+		count := n / goroutines
+		if i < n%goroutines {
+			count++
+		}
+		wg.Add(1)
+		go func(op func(), count int) {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				op()
+			}
+		}(op, count)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	return benchResult{
+		n:      n,
+		time:   elapsed,
+		allocs: after.Mallocs - before.Mallocs,
+		bytes:  after.TotalAlloc - before.TotalAlloc,
+	}
+}
+
+func runBench(args []string) {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	pattern := flags.String("run", ".", "Only run benchmarks whose name (e.g. Query/category) matches this regular expression")
+	benchTime := flags.Duration("benchtime", time.Second, "Approximate run time of each benchmark")
+	flags.Usage = func() {
+		fmt.Println("Usage: unicode_range_finder bench [options]")
+		fmt.Println("\nThe same benchmarks can be run with go test -bench in the source tree.")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+
+	matcher, err := regexp.Compile(*pattern)
+	if err != nil {
+		panic(err)
+	}
+	for _, bm := range benchmarks() {
+		if !matcher.MatchString(bm.name) {
+			continue
+		}
+		fmt.Printf("%-36v %v\n", bm.name, measureBenchmark(bm, *benchTime))
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"testing"
+)
+
+// runBenchmarks runs the benchmarks in a group (see benchmarks) as
+// sub-benchmarks, or the benchmark with that name if it isn't a group.
+func runBenchmarks(b *testing.B, group string) {
+	for _, bm := range benchmarks() {
+		bm := bm
+		if bm.name == group {
+			runBenchmark(b, bm)
+		} else if name, ok := cutPrefix(bm.name, group+"/"); ok {
+			b.Run(name, func(b *testing.B) { runBenchmark(b, bm) })
+		}
+	}
+}
+
+func runBenchmark(b *testing.B, bm benchmark) {
+	b.ReportAllocs()
+	if bm.parallel {
+		b.RunParallel(func(pb *testing.PB) {
+			op := bm.newOp()
+			for pb.Next() {
+				op()
+			}
+		})
+		return
+	}
+	op := bm.newOp()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		op()
+	}
+}
+
+func BenchmarkDecodeTable(b *testing.B)    { runBenchmarks(b, "DecodeTable") }
+func BenchmarkQuery(b *testing.B)          { runBenchmarks(b, "Query") }
+func BenchmarkWindowQuery(b *testing.B)    { runBenchmarks(b, "WindowQuery") }
+func BenchmarkCachedQuery(b *testing.B)    { runBenchmarks(b, "CachedQuery") }
+func BenchmarkParallelQuery(b *testing.B)  { runBenchmarks(b, "ParallelQuery") }
+func BenchmarkParallelLookup(b *testing.B) { runBenchmarks(b, "ParallelLookup") }
+func BenchmarkEmit(b *testing.B)           { runBenchmarks(b, "Emit") }
+
+// TestBenchmarks runs an iteration of each benchmark, so that one that
+// fails shows up without running them all with -bench.
+func TestBenchmarks(t *testing.T) {
+	for _, bm := range benchmarks() {
+		if result := runBenchmarkN(bm, 1); result.n != 1 {
+			t.Errorf("%v: ran %v iterations", bm.name, result.n)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+// blockAliases are the names of the blocks whose short name (which the UCD
+// stores) differs from their long name, from PropertyValueAliases.txt. The
+// short name comes first, followed by the long name and any other aliases.
+// Every other block's short and long names are the same.
+var blockAliases = [][]string{
+	{"Alchemical", "Alchemical_Symbols"},
+	{"Alphabetic_PF", "Alphabetic_Presentation_Forms"},
+	{"Ancient_Greek_Music", "Ancient_Greek_Musical_Notation"},
+	{"Arabic_Ext_A", "Arabic_Extended_A"},
+	{"Arabic_Ext_B", "Arabic_Extended_B"},
+	{"Arabic_Ext_C", "Arabic_Extended_C"},
+	{"Arabic_Math", "Arabic_Mathematical_Alphabetic_Symbols"},
+	{"Arabic_PF_A", "Arabic_Presentation_Forms_A"},
+	{"Arabic_PF_B", "Arabic_Presentation_Forms_B"},
+	{"Arabic_Sup", "Arabic_Supplement"},
+	{"ASCII", "Basic_Latin"},
+	{"Bamum_Sup", "Bamum_Supplement"},
+	{"Bopomofo_Ext", "Bopomofo_Extended"},
+	{"Braille", "Braille_Patterns"},
+	{"Byzantine_Music", "Byzantine_Musical_Symbols"},
+	{"Cherokee_Sup", "Cherokee_Supplement"},
+	{"CJK", "CJK_Unified_Ideographs"},
+	{"CJK_Compat", "CJK_Compatibility"},
+	{"CJK_Compat_Forms", "CJK_Compatibility_Forms"},
+	{"CJK_Compat_Ideographs", "CJK_Compatibility_Ideographs"},
+	{"CJK_Compat_Ideographs_Sup", "CJK_Compatibility_Ideographs_Supplement"},
+	{"CJK_Ext_A", "CJK_Unified_Ideographs_Extension_A"},
+	{"CJK_Ext_B", "CJK_Unified_Ideographs_Extension_B"},
+	{"CJK_Ext_C", "CJK_Unified_Ideographs_Extension_C"},
+	{"CJK_Ext_D", "CJK_Unified_Ideographs_Extension_D"},
+	{"CJK_Ext_E", "CJK_Unified_Ideographs_Extension_E"},
+	{"CJK_Ext_F", "CJK_Unified_Ideographs_Extension_F"},
+	{"CJK_Ext_G", "CJK_Unified_Ideographs_Extension_G"},
+	{"CJK_Ext_H", "CJK_Unified_Ideographs_Extension_H"},
+	{"CJK_Radicals_Sup", "CJK_Radicals_Supplement"},
+	{"CJK_Symbols", "CJK_Symbols_And_Punctuation"},
+	{"Compat_Jamo", "Hangul_Compatibility_Jamo"},
+	{"Counting_Rod", "Counting_Rod_Numerals"},
+	{"Cuneiform_Numbers", "Cuneiform_Numbers_And_Punctuation"},
+	{"Cyrillic_Ext_A", "Cyrillic_Extended_A"},
+	{"Cyrillic_Ext_B", "Cyrillic_Extended_B"},
+	{"Cyrillic_Ext_C", "Cyrillic_Extended_C"},
+	{"Cyrillic_Ext_D", "Cyrillic_Extended_D"},
+	{"Cyrillic_Sup", "Cyrillic_Supplement", "Cyrillic_Supplementary"},
+	{"Devanagari_Ext", "Devanagari_Extended"},
+	{"Devanagari_Ext_A", "Devanagari_Extended_A"},
+	{"Diacriticals", "Combining_Diacritical_Marks"},
+	{"Diacriticals_Ext", "Combining_Diacritical_Marks_Extended"},
+	{"Diacriticals_For_Symbols", "Combining_Diacritical_Marks_For_Symbols", "Combining_Marks_For_Symbols"},
+	{"Diacriticals_Sup", "Combining_Diacritical_Marks_Supplement"},
+	{"Domino", "Domino_Tiles"},
+	{"Enclosed_Alphanum", "Enclosed_Alphanumerics"},
+	{"Enclosed_Alphanum_Sup", "Enclosed_Alphanumeric_Supplement"},
+	{"Enclosed_CJK", "Enclosed_CJK_Letters_And_Months"},
+	{"Enclosed_Ideographic_Sup", "Enclosed_Ideographic_Supplement"},
+	{"Ethiopic_Ext", "Ethiopic_Extended"},
+	{"Ethiopic_Ext_A", "Ethiopic_Extended_A"},
+	{"Ethiopic_Ext_B", "Ethiopic_Extended_B"},
+	{"Ethiopic_Sup", "Ethiopic_Supplement"},
+	{"Geometric_Shapes_Ext", "Geometric_Shapes_Extended"},
+	{"Georgian_Ext", "Georgian_Extended"},
+	{"Georgian_Sup", "Georgian_Supplement"},
+	{"Glagolitic_Sup", "Glagolitic_Supplement"},
+	{"Greek", "Greek_And_Coptic"},
+	{"Greek_Ext", "Greek_Extended"},
+	{"Half_And_Full_Forms", "Halfwidth_And_Fullwidth_Forms"},
+	{"Half_Marks", "Combining_Half_Marks"},
+	{"Hangul", "Hangul_Syllables"},
+	{"High_PU_Surrogates", "High_Private_Use_Surrogates"},
+	{"IDC", "Ideographic_Description_Characters"},
+	{"Ideographic_Symbols", "Ideographic_Symbols_And_Punctuation"},
+	{"Indic_Number_Forms", "Common_Indic_Number_Forms"},
+	{"IPA_Ext", "IPA_Extensions"},
+	{"Jamo", "Hangul_Jamo"},
+	{"Jamo_Ext_A", "Hangul_Jamo_Extended_A"},
+	{"Jamo_Ext_B", "Hangul_Jamo_Extended_B"},
+	{"Kana_Ext_A", "Kana_Extended_A"},
+	{"Kana_Ext_B", "Kana_Extended_B"},
+	{"Kana_Sup", "Kana_Supplement"},
+	{"Kangxi", "Kangxi_Radicals"},
+	{"Katakana_Ext", "Katakana_Phonetic_Extensions"},
+	{"Latin_1_Sup", "Latin_1_Supplement", "Latin_1"},
+	{"Latin_Ext_A", "Latin_Extended_A"},
+	{"Latin_Ext_Additional", "Latin_Extended_Additional"},
+	{"Latin_Ext_B", "Latin_Extended_B"},
+	{"Latin_Ext_C", "Latin_Extended_C"},
+	{"Latin_Ext_D", "Latin_Extended_D"},
+	{"Latin_Ext_E", "Latin_Extended_E"},
+	{"Latin_Ext_F", "Latin_Extended_F"},
+	{"Latin_Ext_G", "Latin_Extended_G"},
+	{"Lisu_Sup", "Lisu_Supplement"},
+	{"Mahjong", "Mahjong_Tiles"},
+	{"Math_Alphanum", "Mathematical_Alphanumeric_Symbols"},
+	{"Math_Operators", "Mathematical_Operators"},
+	{"Meetei_Mayek_Ext", "Meetei_Mayek_Extensions"},
+	{"Misc_Arrows", "Miscellaneous_Symbols_And_Arrows"},
+	{"Misc_Math_Symbols_A", "Miscellaneous_Mathematical_Symbols_A"},
+	{"Misc_Math_Symbols_B", "Miscellaneous_Mathematical_Symbols_B"},
+	{"Misc_Pictographs", "Miscellaneous_Symbols_And_Pictographs"},
+	{"Misc_Symbols", "Miscellaneous_Symbols"},
+	{"Misc_Technical", "Miscellaneous_Technical"},
+	{"Modifier_Letters", "Spacing_Modifier_Letters"},
+	{"Mongolian_Sup", "Mongolian_Supplement"},
+	{"Music", "Musical_Symbols"},
+	{"Myanmar_Ext_A", "Myanmar_Extended_A"},
+	{"Myanmar_Ext_B", "Myanmar_Extended_B"},
+	{"NB", "No_Block"},
+	{"OCR", "Optical_Character_Recognition"},
+	{"Phaistos", "Phaistos_Disc"},
+	{"Phonetic_Ext", "Phonetic_Extensions"},
+	{"Phonetic_Ext_Sup", "Phonetic_Extensions_Supplement"},
+	{"PUA", "Private_Use_Area", "Private_Use"},
+	{"Punctuation", "General_Punctuation"},
+	{"Rumi", "Rumi_Numeral_Symbols"},
+	{"Small_Forms", "Small_Form_Variants"},
+	{"Small_Kana_Ext", "Small_Kana_Extension"},
+	{"Sundanese_Sup", "Sundanese_Supplement"},
+	{"Sup_Arrows_A", "Supplemental_Arrows_A"},
+	{"Sup_Arrows_B", "Supplemental_Arrows_B"},
+	{"Sup_Arrows_C", "Supplemental_Arrows_C"},
+	{"Sup_Math_Operators", "Supplemental_Mathematical_Operators"},
+	{"Sup_PUA_A", "Supplementary_Private_Use_Area_A"},
+	{"Sup_PUA_B", "Supplementary_Private_Use_Area_B"},
+	{"Sup_Punctuation", "Supplemental_Punctuation"},
+	{"Sup_Symbols_And_Pictographs", "Supplemental_Symbols_And_Pictographs"},
+	{"Super_And_Sub", "Superscripts_And_Subscripts"},
+	{"Symbols_And_Pictographs_Ext_A", "Symbols_And_Pictographs_Extended_A"},
+	{"Syriac_Sup", "Syriac_Supplement"},
+	{"Tai_Xuan_Jing", "Tai_Xuan_Jing_Symbols"},
+	{"Tamil_Sup", "Tamil_Supplement"},
+	{"Tangut_Sup", "Tangut_Supplement"},
+	{"Transport_And_Map", "Transport_And_Map_Symbols"},
+	{"UCAS", "Unified_Canadian_Aboriginal_Syllabics", "Canadian_Syllabics"},
+	{"UCAS_Ext", "Unified_Canadian_Aboriginal_Syllabics_Extended"},
+	{"UCAS_Ext_A", "Unified_Canadian_Aboriginal_Syllabics_Extended_A"},
+	{"Vedic_Ext", "Vedic_Extensions"},
+	{"VS", "Variation_Selectors"},
+	{"VS_Sup", "Variation_Selectors_Supplement"},
+	{"Yijing", "Yijing_Hexagram_Symbols"},
+	{"Znamenny_Music", "Znamenny_Musical_Notation"},
+}
+
+// blockCode returns the short name of a block given any of its names, using
+// loose matching (so "CJK Unified Ideographs", "cjk-unified-ideographs", and
+// "CJK" are all "CJK").
+func blockCode(name string) string {
+	name = looseName(name)
+	for _, aliases := range blockAliases {
+		for _, alias := range aliases {
+			if looseName(alias) == name {
+				return aliases[0]
+			}
+		}
+	}
+	return name
+}
+
+// attributeValues returns every value an attribute can have: the ones in the
+// embedded table, plus any from overlays.
+func attributeValues(attribute string) (values []string) {
+	if table, ok := generatedTables[attribute]; ok {
+		values = append(values, table.Values...)
+	}
+	for _, entry := range overlayEntries {
+		if entry.Attribute == attribute {
+			values = append(values, entry.Value)
+		}
+	}
+	return
+}
+
+// blockMatcher matches the codepoints in a block. The block values that match
+// are worked out up front, so that matching is just a lookup.
+func blockMatcher(name string) (CodepointMatcher, bool) {
+	code := looseName(blockCode(name))
+	matching := map[string]bool{}
+	for _, value := range attributeValues("blk") {
+		if looseName(blockCode(value)) == code {
+			matching[value] = true
+		}
+	}
+	if len(matching) == 0 {
+		requireAttributes("block="+name, "blk")
+		return nil, false
+	}
+	return func(cp Codepoint) bool {
+		return matching[cp.Block]
+	}, true
+}
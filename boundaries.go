@@ -0,0 +1,89 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"io"
+	"strings"
+)
+
+func init() {
+	outputFormats["boundaries"] = outputFormat{
+		description: "Sorted range boundaries (each range's start and end+1), for binary search based classifiers",
+		emit:        emitBoundaries,
+	}
+}
+
+// rangeBoundaries returns the start and end+1 of each range. A codepoint is
+// in the set if the number of boundaries less than or equal to it is odd.
+func rangeBoundaries(ranges Ranges) (boundaries []rune) {
+	for _, r := range ranges {
+		if len(boundaries) > 0 && boundaries[len(boundaries)-1] == r.Begin {
+			// Adjacent ranges (e.g. after -max-span) share a boundary, which
+			// would otherwise cancel out.
+			boundaries = boundaries[:len(boundaries)-1]
+		} else {
+			boundaries = append(boundaries, r.Begin)
+		}
+		boundaries = append(boundaries, r.End+1)
+	}
+	return
+}
+
+func emitBoundaries(w io.Writer, ranges Ranges, opts *OutputOptions) error {
+	var entries []string
+	for _, boundary := range rangeBoundaries(ranges) {
+		if opts.Numbers.Decimal {
+			entries = append(entries, opts.Numbers.digits(boundary, 1))
+		} else {
+			entries = append(entries, "0x"+opts.Numbers.digits(boundary, 1))
+		}
+	}
+	return writeWrappedList(w, entries, opts.Leadup, opts.HighCol)
+}
+
+// writeWrappedList writes a comma separated list, wrapping lines before they
+// go past highCol and aligning them after the leadup.
+func writeWrappedList(w io.Writer, entries []string, leadup string, highCol int) error {
+	sb := strings.Builder{}
+	sb.WriteString(leadup)
+	col := len(leadup)
+	for i, entry := range entries {
+		if i < len(entries)-1 {
+			entry += ","
+		}
+		if i > 0 {
+			if highCol > 0 && col+1+len(entry) > highCol {
+				sb.WriteString("\n")
+				sb.WriteString(strings.Repeat(" ", len(leadup)))
+				col = len(leadup)
+			} else {
+				sb.WriteString(" ")
+				col++
+			}
+		}
+		sb.WriteString(entry)
+		col += len(entry)
+	}
+	sb.WriteString("\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,196 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	subcommands["bundle"] = subcommand{
+		description: "Generate one Go file (and its test) holding every table in a manifest",
+		run:         runBundle,
+	}
+}
+
+// BundleEntry is one named set in a bundle manifest. Kind is "func" for a
+// func(rune) bool matcher, or "table" for a *unicode.RangeTable.
+type BundleEntry struct {
+	Kind   string
+	Name   string
+	Query  string
+	Ranges Ranges
+}
+
+// parseBundleManifest reads a manifest, which has one "<kind> <Name> =
+// <search params>" line per entry:
+//
+//	# Lines starting with # are comments.
+//	func IsIdentStart = cat=L ch=_
+//	table Whitespace = cat=Zs cp=9
+func parseBundleManifest(path string) (entries []BundleEntry, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	names := map[string]int{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		declaration, query, ok := strings.Cut(text, "=")
+		fields := strings.Fields(declaration)
+		if !ok || len(fields) != 2 || strings.TrimSpace(query) == "" {
+			return nil, fmt.Errorf("%v:%v: expected <func or table> <Name> = <search params>", path, line)
+		}
+		kind, name := fields[0], fields[1]
+		if kind != "func" && kind != "table" {
+			return nil, fmt.Errorf("%v:%v: %v: unknown kind (must be func or table)", path, line, kind)
+		}
+		if previous, ok := names[name]; ok {
+			return nil, fmt.Errorf("%v:%v: %v is already defined on line %v", path, line, name, previous)
+		}
+		names[name] = line
+		entries = append(entries, BundleEntry{Kind: kind, Name: name, Query: strings.TrimSpace(query)})
+	}
+	return entries, scanner.Err()
+}
+
+func bundleDoc(entry BundleEntry) string {
+	switch entry.Kind {
+	case "func":
+		return fmt.Sprintf("%v reports whether r matches: %v", entry.Name, entry.Query)
+	default:
+		return fmt.Sprintf("%v matches: %v", entry.Name, entry.Query)
+	}
+}
+
+func generateGoBundle(pkg string, entries []BundleEntry) ([]byte, error) {
+	sb := strings.Builder{}
+	var imports []string
+	for _, entry := range entries {
+		if entry.Kind == "table" {
+			imports = append(imports, "unicode")
+			break
+		}
+	}
+	writeGoHeader(&sb, pkg, imports...)
+	for _, entry := range entries {
+		if entry.Kind == "func" {
+			writeGoMatcherFunc(&sb, GoMatcherFunc{Name: entry.Name, Doc: bundleDoc(entry), Ranges: entry.Ranges})
+		} else {
+			writeGoRangeTable(&sb, entry.Name, bundleDoc(entry), entry.Ranges)
+		}
+	}
+	return format.Source([]byte(sb.String()))
+}
+
+// generateGoBundleTest checks the edges of every range: the first and last
+// codepoints must match, and the ones just outside must not.
+func generateGoBundleTest(pkg string, entries []BundleEntry) ([]byte, error) {
+	sb := strings.Builder{}
+	imports := []string{"testing"}
+	for _, entry := range entries {
+		if entry.Kind == "table" {
+			imports = append(imports, "unicode")
+			break
+		}
+	}
+	writeGoHeader(&sb, pkg, imports...)
+	for _, entry := range entries {
+		contains := entry.Name + "(r)"
+		if entry.Kind == "table" {
+			contains = fmt.Sprintf("unicode.Is(%v, r)", entry.Name)
+		}
+		sb.WriteString(fmt.Sprintf("\nfunc Test%v(t *testing.T) {\n", exportedName(entry.Name)))
+		sb.WriteString("\tfor _, test := range []struct {\n\t\tr    rune\n\t\twant bool\n\t}{\n")
+		for _, r := range entry.Ranges {
+			if r.Begin > 0 {
+				sb.WriteString(fmt.Sprintf("\t\t{0x%04x, false},\n", r.Begin-1))
+			}
+			sb.WriteString(fmt.Sprintf("\t\t{0x%04x, true},\n", r.Begin))
+			if r.End != r.Begin {
+				sb.WriteString(fmt.Sprintf("\t\t{0x%04x, true},\n", r.End))
+			}
+			if r.End < maxCodepoint {
+				sb.WriteString(fmt.Sprintf("\t\t{0x%04x, false},\n", r.End+1))
+			}
+		}
+		sb.WriteString("\t} {\n")
+		sb.WriteString(fmt.Sprintf("\t\tr := test.r\n\t\tif got := %v; got != test.want {\n", contains))
+		sb.WriteString(fmt.Sprintf("\t\t\tt.Errorf(\"%v(%%U) = %%v, want %%v\", r, got, test.want)\n", entry.Name))
+		sb.WriteString("\t\t}\n\t}\n}\n")
+	}
+
+	return format.Source([]byte(sb.String()))
+}
+
+func runBundle(args []string) {
+	flags := flag.NewFlagSet("bundle", flag.ExitOnError)
+	manifest := flags.String("manifest", "", "Manifest listing the tables to generate")
+	pkg := flags.String("package", "main", "Package of the generated Go files")
+	outPath := flags.String("o", "", "Go file to write (the test goes next to it, ending in _test.go)")
+	flags.Usage = func() {
+		fmt.Println("Usage: unicode_range_finder bundle -manifest <file> -o <file.go> [options]")
+		fmt.Println("\nEach manifest line is \"<func or table> <Name> = <search params>\".")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+	if *manifest == "" || *outPath == "" {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	entries, err := parseBundleManifest(*manifest)
+	if err != nil {
+		panic(err)
+	}
+	for i := range entries {
+		entries[i].Ranges = searchRanges(entries[i].Query, 0, maxCodepoint)
+	}
+
+	source, err := generateGoBundle(*pkg, entries)
+	if err != nil {
+		panic(err)
+	}
+	testSource, err := generateGoBundleTest(*pkg, entries)
+	if err != nil {
+		panic(err)
+	}
+	testPath := strings.TrimSuffix(*outPath, filepath.Ext(*outPath)) + "_test.go"
+	if err := os.WriteFile(*outPath, source, 0644); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(testPath, testSource, 0644); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Query results are cached in the user's cache directory, so that build
+// scripts that run the same query over and over (e.g. once per output format)
+// only pay for the scan once. The cache is best effort: anything that goes
+// wrong with it just means the query gets run. It's only used by the command
+// line tool (see Main), so that programs using the package as a library don't
+// write to the user's cache directory behind their backs.
+var resultCacheEnabled = false
+
+// Cached results that haven't been used for resultCacheMaxAge are removed
+// whenever a new one is stored, and after that the least recently used ones
+// go until there are at most resultCacheMaxEntries.
+const (
+	resultCacheMaxAge     = 30 * 24 * time.Hour
+	resultCacheMaxEntries = 1000
+)
+
+// resultCacheKey identifies a query result. Besides the query and the Unicode
+// data (by version and checksum, since a data file can be loaded), it
+// includes the executable itself, since a different build can have different
+// presets or matchers. Results that depend on files loaded at runtime
+// (overlays, private use sets, emoji annotations, the DUCET, fonts, keyboard
+// layouts) or on registered custom matchers aren't cached.
+func resultCacheKey(q string, lowCP rune, highCP rune) (string, bool) {
+	if !resultCacheEnabled || len(overlayEntries) > 0 || len(puaSets) > 0 || len(emojiAnnotations) > 0 || len(collationElements) > 0 || len(customMatchers) > 0 || fontsLoaded > 0 || len(keyboardLayouts) > 0 {
+		return "", false
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return "", false
+	}
+	info, err := os.Stat(executable)
+	if err != nil {
+		return "", false
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%v\n%v %v\n%v %v\n%v %v\n%v-%v\n", normalizeQuery(q), generatedUnicodeVersion, generatedChecksum,
+		executable, info.Size(), info.ModTime().UnixNano(), len(generatedTables), lowCP, highCP)
+	return hex.EncodeToString(hash.Sum(nil)), true
+}
+
+func resultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "unicode_range_finder"), nil
+}
+
+func resultCachePath(key string) (string, error) {
+	dir, err := resultCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, key+".json"), nil
+}
+
+func loadCachedResult(key string) (ranges Ranges, ok bool) {
+	path, err := resultCachePath(key)
+	if err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var pairs [][2]rune
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return nil, false
+	}
+	for _, pair := range pairs {
+		ranges = append(ranges, Range{Begin: pair[0], End: pair[1]})
+	}
+	// The modification time doubles as the last use, for pruning.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return ranges, true
+}
+
+func storeCachedResult(key string, ranges Ranges) {
+	path, err := resultCachePath(key)
+	if err != nil {
+		return
+	}
+	pairs := make([][2]rune, 0, len(ranges))
+	for _, r := range ranges {
+		pairs = append(pairs, [2]rune{r.Begin, r.End})
+	}
+	data, err := json.Marshal(pairs)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	if writeFileAtomically(path, data) == nil {
+		pruneResultCache(filepath.Dir(path), time.Now())
+	}
+}
+
+// cachedResultFiles lists the cached results in dir, along with any
+// temporary files that a crashed run left behind. Anything else in there
+// (such as downloaded UCD files) isn't part of the result cache.
+func cachedResultFiles(dir string) (files []os.FileInfo, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.Contains(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+	}
+	return files, nil
+}
+
+// pruneResultCache removes the cached results in dir that are older than
+// resultCacheMaxAge, and then the least recently used ones past
+// resultCacheMaxEntries.
+func pruneResultCache(dir string, now time.Time) {
+	files, err := cachedResultFiles(dir)
+	if err != nil {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+	for i, file := range files {
+		if i >= resultCacheMaxEntries || now.Sub(file.ModTime()) > resultCacheMaxAge {
+			os.Remove(filepath.Join(dir, file.Name()))
+		}
+	}
+}
+
+// clearResultCache removes every cached result (see -clear-cache).
+func clearResultCache() error {
+	dir, err := resultCacheDir()
+	if err != nil {
+		return err
+	}
+	files, err := cachedResultFiles(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, file := range files {
+		if err := os.Remove(filepath.Join(dir, file.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFileAtomically writes to a temporary file first and renames it into
+// place, so that a concurrent run never sees a partial file.
+func writeFileAtomically(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func cacheDirFiles(t *testing.T, dir string) (names []string) {
+	t.Helper()
+	files, err := cachedResultFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	sort.Strings(names)
+	return
+}
+
+func TestPruneResultCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	age := func(name string, d time.Duration) {
+		path := writeTestFile(t, dir, name, "[]")
+		if err := os.Chtimes(path, now.Add(-d), now.Add(-d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	age("fresh.json", time.Hour)
+	age("stale.json", resultCacheMaxAge+time.Hour)
+	age("stale.json-1234", resultCacheMaxAge+time.Hour)
+	age("unrelated.zip", resultCacheMaxAge+time.Hour)
+	if err := os.Mkdir(filepath.Join(dir, "ucd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	pruneResultCache(dir, now)
+	if names := fmt.Sprint(cacheDirFiles(t, dir)); names != "[fresh.json]" {
+		t.Errorf("expected only fresh.json to be left but got %v", names)
+	}
+	for _, name := range []string{"unrelated.zip", "ucd"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("pruning removed %v", name)
+		}
+	}
+
+	// Past the limit, the least recently used go first.
+	for i := 0; i < resultCacheMaxEntries+5; i++ {
+		age(fmt.Sprintf("%04d.json", i), time.Duration(i)*time.Minute)
+	}
+	pruneResultCache(dir, now)
+	names := cacheDirFiles(t, dir)
+	if len(names) != resultCacheMaxEntries {
+		t.Fatalf("expected %v entries but got %v", resultCacheMaxEntries, len(names))
+	}
+	for _, name := range []string{"0000.json", "fresh.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("pruning removed the recently used %v", name)
+		}
+	}
+	oldest := fmt.Sprintf("%04d.json", resultCacheMaxEntries+4)
+	if _, err := os.Stat(filepath.Join(dir, oldest)); err == nil {
+		t.Errorf("pruning kept the least recently used %v", oldest)
+	}
+}
+
+func TestResultCacheUse(t *testing.T) {
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	dir, err := resultCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(dir, cacheHome) {
+		t.Skip("the user cache directory doesn't follow XDG_CACHE_HOME here")
+	}
+
+	storeCachedResult("key", rangesOf("41-5a"))
+	path, _ := resultCachePath("key")
+	old := time.Now().Add(-resultCacheMaxAge + time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if ranges, ok := loadCachedResult("key"); !ok || ranges.String() != rangesOf("41-5a").String() {
+		t.Fatalf("expected 41-5a but got %v (%v)", ranges, ok)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().After(old) {
+		t.Errorf("loading a cached result didn't mark it as used")
+	}
+
+	env := []string{"XDG_CACHE_HOME=" + cacheHome}
+	if _, stderr, err := runMain(t, env, "-format", "jsonl", "cat=Lu"); err != nil {
+		t.Fatalf("%v: %v", err, stderr)
+	}
+	if len(cacheDirFiles(t, dir)) < 2 {
+		t.Fatalf("the query's result wasn't cached")
+	}
+	if stdout, stderr, err := runMain(t, env, "-clear-cache"); err != nil || stdout != "" {
+		t.Fatalf("-clear-cache: %v: %q %v", err, stdout, stderr)
+	}
+	if names := cacheDirFiles(t, dir); len(names) != 0 {
+		t.Errorf("-clear-cache left %v", names)
+	}
+}
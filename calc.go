@@ -0,0 +1,113 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func init() {
+	subcommands["calc"] = subcommand{
+		description: "Combine range lists with union, intersection, and difference, without looking at the Unicode data",
+		run:         runCalc,
+	}
+}
+
+// calcOperand evaluates a range list, or with a leading @, a file of ranges.
+func calcOperand(words []string) (Ranges, error) {
+	if len(words) == 1 && strings.HasPrefix(words[0], "@") {
+		return loadRangesFile(words[0][1:])
+	}
+	return parseRangeList(strings.Join(words, " "))
+}
+
+// evaluateCalc evaluates an expression of range lists and operators, which
+// are the set operations (see setOperation). The operators are applied from
+// left to right.
+func evaluateCalc(expression string) (result Ranges, err error) {
+	var operator func(a Ranges, b Ranges) Ranges
+	var operand []string
+	applyOperand := func() error {
+		if len(operand) == 0 {
+			return fmt.Errorf("expected a range list")
+		}
+		ranges, err := calcOperand(operand)
+		if err != nil {
+			return err
+		}
+		if operator == nil {
+			result = ranges
+		} else {
+			result = operator(result, ranges)
+		}
+		operand = nil
+		return nil
+	}
+
+	for _, word := range strings.Fields(expression) {
+		if _, op, ok := setOperation(word); ok {
+			if err := applyOperand(); err != nil {
+				return nil, fmt.Errorf("%w before %v", err, word)
+			}
+			operator = op
+			continue
+		}
+		operand = append(operand, word)
+	}
+	if err := applyOperand(); err != nil {
+		return nil, fmt.Errorf("%w at the end of the expression", err)
+	}
+	return
+}
+
+func runCalc(args []string) {
+	flags := flag.NewFlagSet("calc", flag.ExitOnError)
+	format := flags.String("format", "ebnf", "Output format for the result")
+	leadup := flags.String("leadup", "", "Leadup text to print and align to")
+	highCol := flags.Int("highcol", 80, "Highest column to print at (columns start at 1)")
+	flags.Usage = func() {
+		fmt.Println(`Usage: unicode_range_finder calc [options] "<ranges> union <ranges> difference <ranges> ..."`)
+		fmt.Println("\nRange lists can be written as 41-5A, 0041..005A, U+0041-U+005A, 0x41-0x5A, or")
+		fmt.Println("[#x41-#x5A] | #x5F, separated by spaces or commas. @file reads a file of ranges.")
+		fmt.Println("Operators are union (or, +), intersection (intersect, and, &), difference")
+		fmt.Println("(subtract, minus, except, diff), and symmetric-difference (symdiff, xor), and are")
+		fmt.Println("applied from left to right.")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	result, err := evaluateCalc(strings.Join(flags.Args(), " "))
+	if err != nil {
+		panic(err)
+	}
+	opts := &OutputOptions{Leadup: *leadup, HighCol: *highCol}
+	if err := emitRanges(os.Stdout, *format, result, opts); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func init() {
+	subcommands["casemap"] = subcommand{
+		description: "Generate upper/lower/title case mapping tables for the matched codepoints",
+		run:         runCasemap,
+	}
+}
+
+// subcommandRanges runs the search params left over after a subcommand's
+// flags. No search params means every codepoint.
+func subcommandRanges(args []string) Ranges {
+	if len(args) == 0 {
+		return Ranges{{Begin: 0, End: maxCodepoint}}
+	}
+	return searchRanges(strings.Join(args, " "), 0, maxCodepoint)
+}
+
+// caseMappingAttributes returns the upper, lower, and title case mapping
+// attributes, simple or full.
+func caseMappingAttributes(full bool) []string {
+	if full {
+		return []string{"uc", "lc", "tc"}
+	}
+	return []string{"suc", "slc", "stc"}
+}
+
+// caseMappingTable builds a table of every codepoint in ranges that has a
+// case mapping to something other than itself. Full mappings come from
+// SpecialCasing.txt and can map to more than one codepoint.
+func caseMappingTable(ranges Ranges, full bool, name string) *MappingTable {
+	attributes := caseMappingAttributes(full)
+	kind := ColumnRune
+	doc := "Simple case mappings (UnicodeData.txt)."
+	if full {
+		kind = ColumnRunes
+		doc = "Full case mappings (UnicodeData.txt plus SpecialCasing.txt)."
+	}
+
+	table := &MappingTable{
+		Name: name,
+		Doc:  doc,
+		Columns: []MappingColumn{
+			{Name: "Upper", Kind: kind},
+			{Name: "Lower", Kind: kind},
+			{Name: "Title", Kind: kind},
+		},
+	}
+	for _, r := range ranges {
+		for cp := r.Begin; cp <= r.End; cp++ {
+			row := MappingRow{Codepoint: cp}
+			changes := false
+			for _, attribute := range attributes {
+				mapped := codepointSequence(attribute, cp)
+				if len(mapped) != 1 || mapped[0] != cp {
+					changes = true
+				}
+				if full {
+					row.Values = append(row.Values, mapped)
+				} else {
+					row.Values = append(row.Values, mapped[0])
+				}
+			}
+			if changes {
+				table.Rows = append(table.Rows, row)
+			}
+		}
+	}
+	return table
+}
+
+func runCasemap(args []string) {
+	flags := flag.NewFlagSet("casemap", flag.ExitOnError)
+	format := flags.String("format", "go", "Table format: go, c, or json")
+	full := flags.Bool("full", false, "Use full case mappings, including the SpecialCasing.txt entries")
+	pkg := flags.String("package", "main", "Package of the generated Go file")
+	name := flags.String("name", "CaseMappings", "Name of the generated table")
+	flags.Usage = func() {
+		fmt.Println("Usage: unicode_range_finder casemap [options] [search params]")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+	requireAttributes("casemap", caseMappingAttributes(*full)...)
+
+	table := caseMappingTable(subcommandRanges(flags.Args()), *full, *name)
+	if err := emitMappingTable(os.Stdout, *format, *pkg, table); err != nil {
+		panic(err)
+	}
+}
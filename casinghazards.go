@@ -0,0 +1,142 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func init() {
+	subcommands["casing-hazards"] = subcommand{
+		description: "Report the matched codepoints whose case mappings depend on the locale or context, or don't round trip",
+		run:         runCasingHazards,
+	}
+}
+
+// CasingHazard is a reason that case conversion or case insensitive
+// comparison of a codepoint can go wrong. Locales is empty for hazards that
+// apply everywhere.
+type CasingHazard struct {
+	Locales string
+	Issue   string
+}
+
+// knownCasingHazards come from the conditional mappings in SpecialCasing.txt,
+// the Turkic (T) entries of CaseFolding.txt, and the mappings that don't
+// round trip. None of this is in the UCD XML.
+var knownCasingHazards = map[rune][]CasingHazard{
+	0x0049: {
+		{"tr, az", "lowercases to dotless ı (U+0131) instead of i"},
+		{"lt", "lowercases to i + U+0307 when followed by accents above"},
+	},
+	0x004a: {{"lt", "lowercases to j + U+0307 when followed by accents above"}},
+	0x0069: {{"tr, az", "uppercases to dotted İ (U+0130) instead of I"}},
+	0x00cc: {{"lt", "lowercases to i + U+0307 U+0300"}},
+	0x00cd: {{"lt", "lowercases to i + U+0307 U+0301"}},
+	0x00df: {{"", "uppercases to SS and full case folds to ss"}},
+	0x0128: {{"lt", "lowercases to i + U+0307 U+0303"}},
+	0x012e: {{"lt", "lowercases to į + U+0307 when followed by accents above"}},
+	0x0130: {
+		{"", "lowercases and full case folds to i + U+0307, and has no simple case folding"},
+		{"tr, az", "lowercases and case folds to i"},
+	},
+	0x0131: {{"", "uppercases to I, which lowercases to i (except in tr and az)"}},
+	0x017f: {{"", "long s uppercases to S and case folds to s"}},
+	0x0307: {
+		{"tr, az", "removed when lowercasing I + U+0307"},
+		{"lt", "removed when uppercasing a soft dotted letter (e.g. i) it follows"},
+	},
+	0x03a3: {{"", "lowercases to final ς (U+03C2) at the end of a word, and σ elsewhere"}},
+	0x03c2: {{"", "uppercases to Σ and case folds to σ, so it doesn't round trip"}},
+	0x1e9e: {{"", "case folds to ss (full) or ß (simple)"}},
+	0x212a: {{"", "Kelvin sign lowercases and case folds to ASCII k"}},
+	0x212b: {{"", "Angstrom sign lowercases and case folds to å (U+00E5)"}},
+}
+
+// casingHazards returns the hazards of cp. Besides the known ones, any full
+// case mapping or folding that expands to more than one codepoint is a
+// hazard, since simple case folding (and code that assumes one codepoint in
+// gives one codepoint out) gets those wrong. That part needs the case
+// attributes in the embedded data.
+func casingHazards(cp rune) []CasingHazard {
+	if hazards, ok := knownCasingHazards[cp]; ok {
+		return hazards
+	}
+	var hazards []CasingHazard
+	for _, mapping := range []struct {
+		attribute string
+		name      string
+	}{
+		{"uc", "uppercases"},
+		{"lc", "lowercases"},
+		{"tc", "titlecases"},
+		{"cf", "full case folds"},
+	} {
+		if mapped := codepointSequence(mapping.attribute, cp); len(mapped) > 1 {
+			hazards = append(hazards, CasingHazard{Issue: fmt.Sprintf("%v to %v codepoints (%v)", mapping.name, len(mapped), codepointList(mapped))})
+		}
+	}
+	return hazards
+}
+
+func runCasingHazards(args []string) {
+	flags := flag.NewFlagSet("casing-hazards", flag.ExitOnError)
+	showRanges := flags.Bool("ranges", false, "Print the ranges of codepoints with hazards instead of the report")
+	format := flags.String("format", "ebnf", "Output format for -ranges")
+	flags.Usage = func() {
+		fmt.Println("Usage: unicode_range_finder casing-hazards [options] [search params]")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+	requireAttributes("casing-hazards", "uc", "lc", "tc", "cf")
+
+	var hazardous Ranges
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODEPOINT\tCHAR\tLOCALES\tISSUE")
+	for _, r := range subcommandRanges(flags.Args()) {
+		for cp := r.Begin; cp <= r.End; cp++ {
+			hazards := casingHazards(cp)
+			if len(hazards) == 0 {
+				continue
+			}
+			hazardous = hazardous.Add(cp)
+			codepoint := getCodepoint(cp)
+			for _, hazard := range hazards {
+				locales := hazard.Locales
+				if locales == "" {
+					locales = "all"
+				}
+				fmt.Fprintf(tw, "U+%04X\t%v\t%v\t%v\n", cp, safeGlyph(&codepoint), locales, hazard.Issue)
+			}
+		}
+	}
+	if *showRanges {
+		if err := emitRanges(os.Stdout, *format, hazardous, &OutputOptions{HighCol: 80}); err != nil {
+			panic(err)
+		}
+		return
+	}
+	tw.Flush()
+}
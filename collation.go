@@ -0,0 +1,180 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CollationElement is one collation element from the DUCET, e.g.
+// [*0201.0020.0002].
+type CollationElement struct {
+	// Variable elements (marked with *) can be shifted or ignored by the
+	// alternate handling options, e.g. to sort "de luge" with "deluge".
+	Variable  bool
+	Primary   uint16
+	Secondary uint16
+	Tertiary  uint16
+}
+
+// collationElements is filled in by -ducet. Only the entries for single
+// codepoints are kept, since contractions don't map to a codepoint set.
+// Codepoints that aren't in the table get implicit weights.
+var collationElements = map[rune][]CollationElement{}
+
+// loadDUCET reads a DUCET file (allkeys.txt from the UCA release).
+func loadDUCET(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.SplitN(text, ";", 2)
+		if len(fields) != 2 || strings.HasPrefix(strings.TrimSpace(text), "@") {
+			continue
+		}
+		codepoints := strings.Fields(fields[0])
+		if len(codepoints) != 1 {
+			continue
+		}
+		cp, err := strconv.ParseUint(codepoints[0], 16, 32)
+		if err != nil {
+			return fmt.Errorf("%v:%v: %v: malformed codepoint", path, line, codepoints[0])
+		}
+		elements, err := parseCollationElements(fields[1])
+		if err != nil {
+			return fmt.Errorf("%v:%v: %w", path, line, err)
+		}
+		collationElements[rune(cp)] = elements
+	}
+	return scanner.Err()
+}
+
+// parseCollationElements parses a list of collation elements, e.g.
+// [.1FA2.0020.0008][.0000.0111.0002].
+func parseCollationElements(text string) (elements []CollationElement, err error) {
+	text = strings.TrimSpace(text)
+	for len(text) > 0 {
+		end := strings.IndexByte(text, ']')
+		if text[0] != '[' || end < 0 {
+			return nil, fmt.Errorf("%v: malformed collation element", text)
+		}
+		element := text[1:end]
+		text = strings.TrimSpace(text[end+1:])
+		if len(element) == 0 || (element[0] != '.' && element[0] != '*') {
+			return nil, fmt.Errorf("[%v]: malformed collation element", element)
+		}
+		weights := strings.Split(element[1:], ".")
+		if len(weights) < 3 {
+			return nil, fmt.Errorf("[%v]: malformed collation element", element)
+		}
+		var values [3]uint64
+		for i := range values {
+			if values[i], err = strconv.ParseUint(weights[i], 16, 16); err != nil {
+				return nil, fmt.Errorf("[%v]: malformed collation element", element)
+			}
+		}
+		elements = append(elements, CollationElement{
+			Variable:  element[0] == '*',
+			Primary:   uint16(values[0]),
+			Secondary: uint16(values[1]),
+			Tertiary:  uint16(values[2]),
+		})
+	}
+	return
+}
+
+func checkDUCETLoaded(param string) {
+	if len(collationElements) == 0 {
+		panic(fmt.Errorf("%v: no DUCET loaded (see -ducet)", param))
+	}
+}
+
+// primaryWeightMatcher matches the codepoints whose collation elements all
+// have a primary weight in a range (e.g. primaryweight=0 for the codepoints
+// that are ignorable at the primary level, or primaryweight=1FA2-1FB8).
+func primaryWeightMatcher(value string) (CodepointMatcher, bool) {
+	checkDUCETLoaded("primaryweight=" + value)
+	lowHi := strings.Split(value, "-")
+	if len(lowHi) > 2 {
+		return nil, false
+	}
+	low, err := strconv.ParseUint(lowHi[0], 16, 16)
+	if err != nil {
+		return nil, false
+	}
+	high := low
+	if len(lowHi) == 2 {
+		if high, err = strconv.ParseUint(lowHi[1], 16, 16); err != nil {
+			return nil, false
+		}
+	}
+	return func(cp Codepoint) bool {
+		elements, ok := collationElements[cp.Codepoint]
+		if !ok {
+			return false
+		}
+		for _, element := range elements {
+			if uint64(element.Primary) < low || uint64(element.Primary) > high {
+				return false
+			}
+		}
+		return true
+	}, true
+}
+
+// collationMatcher matches a kind of DUCET entry: ignorable (all weights are
+// zero) or variable (the first element is marked with *).
+func collationMatcher(kind string) (CodepointMatcher, bool) {
+	checkDUCETLoaded("collation=" + kind)
+	switch kind {
+	case "ignorable":
+		return func(cp Codepoint) bool {
+			elements, ok := collationElements[cp.Codepoint]
+			if !ok {
+				return false
+			}
+			for _, element := range elements {
+				if element.Primary != 0 || element.Secondary != 0 || element.Tertiary != 0 {
+					return false
+				}
+			}
+			return true
+		}, true
+	case "variable":
+		return func(cp Codepoint) bool {
+			elements := collationElements[cp.Codepoint]
+			return len(elements) > 0 && elements[0].Variable
+		}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,248 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"strings"
+)
+
+func init() {
+	subcommands["ccc"] = subcommand{
+		description: "Generate canonical combining class tables for the matched codepoints",
+		run:         runCombiningClass,
+	}
+}
+
+// TwoStageTrie is a lookup table for small per codepoint values. The value
+// of cp is Blocks[Index[cp>>Shift]<<Shift | cp&(1<<Shift-1)], and any
+// codepoint past the end of Index has the value 0. Identical blocks are
+// shared, which is what keeps the table small.
+type TwoStageTrie struct {
+	Shift  uint
+	Index  []int
+	Blocks []uint8
+}
+
+func buildTwoStageTrie(ranges Ranges, shift uint, value func(cp rune) uint8) *TwoStageTrie {
+	trie := &TwoStageTrie{Shift: shift}
+	blockSize := rune(1) << shift
+
+	highest := rune(-1)
+	for _, r := range ranges {
+		for cp := r.Begin; cp <= r.End; cp++ {
+			if value(cp) != 0 {
+				highest = cp
+			}
+		}
+	}
+
+	values := make([]uint8, (highest/blockSize+1)*blockSize)
+	for _, r := range ranges {
+		for cp := r.Begin; cp <= r.End && cp <= highest; cp++ {
+			values[cp] = value(cp)
+		}
+	}
+
+	blockIndexes := map[string]int{}
+	for begin := 0; begin < len(values); begin += int(blockSize) {
+		block := values[begin : begin+int(blockSize)]
+		index, ok := blockIndexes[string(block)]
+		if !ok {
+			index = len(trie.Blocks) >> shift
+			blockIndexes[string(block)] = index
+			trie.Blocks = append(trie.Blocks, block...)
+		}
+		trie.Index = append(trie.Index, index)
+	}
+	return trie
+}
+
+func (_this *TwoStageTrie) indexType() (goType string, cType string) {
+	if len(_this.Blocks)>>_this.Shift > 0x100 {
+		return "uint16", "uint16_t"
+	}
+	return "uint8", "uint8_t"
+}
+
+func writeNumbers(sb *strings.Builder, indent string, count int, number func(i int) string) {
+	for i := 0; i < count; i++ {
+		if i%16 == 0 {
+			sb.WriteString(indent)
+		}
+		sb.WriteString(number(i))
+		sb.WriteString(",")
+		if i%16 == 15 || i == count-1 {
+			sb.WriteString("\n")
+		} else {
+			sb.WriteString(" ")
+		}
+	}
+}
+
+func emitGoTrie(w io.Writer, pkg string, name string, doc string, trie *TwoStageTrie) error {
+	prefix := unexportedName(name)
+	indexType, _ := trie.indexType()
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("// Code generated by %v. DO NOT EDIT.\n\n", commandLine()))
+	sb.WriteString(fmt.Sprintf("package %v\n\n", pkg))
+	sb.WriteString(fmt.Sprintf("const %vShift = %v\n\n", prefix, trie.Shift))
+	sb.WriteString(fmt.Sprintf("var %vIndex = [...]%v{\n", prefix, indexType))
+	writeNumbers(&sb, "\t", len(trie.Index), func(i int) string { return fmt.Sprintf("%d", trie.Index[i]) })
+	sb.WriteString("}\n\n")
+	sb.WriteString(fmt.Sprintf("var %vBlocks = [...]uint8{\n", prefix))
+	writeNumbers(&sb, "\t", len(trie.Blocks), func(i int) string { return fmt.Sprintf("%d", trie.Blocks[i]) })
+	sb.WriteString("}\n\n")
+	if doc != "" {
+		sb.WriteString(fmt.Sprintf("// %v returns %v\n", name, doc))
+	}
+	sb.WriteString(fmt.Sprintf(`func %v(r rune) uint8 {
+	i := int(r) >> %vShift
+	if r < 0 || i >= len(%vIndex) {
+		return 0
+	}
+	return %vBlocks[int(%vIndex[i])<<%vShift|int(r)&(1<<%vShift-1)]
+}
+`, name, prefix, prefix, prefix, prefix, prefix, prefix))
+
+	source, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(source)
+	return err
+}
+
+func emitCTrie(w io.Writer, name string, doc string, trie *TwoStageTrie) error {
+	prefix := snakeCase(name)
+	_, indexType := trie.indexType()
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("/* Code generated by %v. DO NOT EDIT. */\n\n", commandLine()))
+	sb.WriteString("#include <stdint.h>\n\n")
+	sb.WriteString(fmt.Sprintf("#define %v_SHIFT %v\n\n", strings.ToUpper(prefix), trie.Shift))
+	sb.WriteString(fmt.Sprintf("static const %v %v_index[] = {\n", indexType, prefix))
+	writeNumbers(&sb, "    ", len(trie.Index), func(i int) string { return fmt.Sprintf("%d", trie.Index[i]) })
+	sb.WriteString("};\n\n")
+	sb.WriteString(fmt.Sprintf("static const uint8_t %v_blocks[] = {\n", prefix))
+	writeNumbers(&sb, "    ", len(trie.Blocks), func(i int) string { return fmt.Sprintf("%d", trie.Blocks[i]) })
+	sb.WriteString("};\n\n")
+	if doc != "" {
+		sb.WriteString(fmt.Sprintf("/* %v returns %v */\n", prefix, doc))
+	}
+	sb.WriteString(fmt.Sprintf(`static inline uint8_t %v(uint32_t cp) {
+    uint32_t i = cp >> %v_SHIFT;
+    if (i >= sizeof(%v_index) / sizeof(%v_index[0])) {
+        return 0;
+    }
+    return %v_blocks[((uint32_t)%v_index[i] << %v_SHIFT) | (cp & ((1u << %v_SHIFT) - 1))];
+}
+`, prefix, strings.ToUpper(prefix), prefix, prefix, prefix, prefix, strings.ToUpper(prefix), strings.ToUpper(prefix)))
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func emitJSONTrie(w io.Writer, name string, doc string, trie *TwoStageTrie) error {
+	blocks := make([]int, len(trie.Blocks))
+	for i, v := range trie.Blocks {
+		blocks[i] = int(v)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"name":            snakeCase(name),
+		"description":     doc,
+		"unicode_version": generatedUnicodeVersion,
+		"shift":           trie.Shift,
+		"index":           trie.Index,
+		"blocks":          blocks,
+	})
+}
+
+func emitTrie(w io.Writer, format string, pkg string, name string, doc string, trie *TwoStageTrie) error {
+	switch format {
+	case "go":
+		return emitGoTrie(w, pkg, name, doc, trie)
+	case "c":
+		return emitCTrie(w, name, doc, trie)
+	case "json":
+		return emitJSONTrie(w, name, doc, trie)
+	}
+	return fmt.Errorf("%v: unknown table format (must be go, c, or json)", format)
+}
+
+func combiningClass(cp rune) uint8 {
+	if cp < 0 || cp > maxCodepoint {
+		return 0
+	}
+	return getCodepoint(cp).CombiningClass
+}
+
+func runCombiningClass(args []string) {
+	flags := flag.NewFlagSet("ccc", flag.ExitOnError)
+	format := flags.String("format", "go", "Table format: go, c, or json")
+	layout := flags.String("layout", "array", "Table layout: array (sorted codepoint/class pairs) or trie (two stage lookup table)")
+	shift := flags.Uint("shift", 7, "log2 of the trie block size")
+	pkg := flags.String("package", "main", "Package of the generated Go file")
+	name := flags.String("name", "CombiningClass", "Name of the generated table (or lookup function, for a trie)")
+	flags.Usage = func() {
+		fmt.Println("Usage: unicode_range_finder ccc [options] [search params]")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+	requireAttributes("ccc", "ccc")
+
+	ranges := subcommandRanges(flags.Args())
+	switch *layout {
+	case "array":
+		table := &MappingTable{
+			Name:    *name,
+			Doc:     "Nonzero canonical combining classes (UnicodeData.txt).",
+			Columns: []MappingColumn{{Name: "Class", Kind: ColumnInt}},
+		}
+		for _, r := range ranges {
+			for cp := r.Begin; cp <= r.End; cp++ {
+				if class := combiningClass(cp); class != 0 {
+					table.Rows = append(table.Rows, MappingRow{Codepoint: cp, Values: []interface{}{int(class)}})
+				}
+			}
+		}
+		if err := emitMappingTable(os.Stdout, *format, *pkg, table); err != nil {
+			panic(err)
+		}
+	case "trie":
+		if *shift < 1 || *shift > 16 {
+			panic(fmt.Errorf("%v: shift must be between 1 and 16", *shift))
+		}
+		trie := buildTwoStageTrie(ranges, *shift, combiningClass)
+		doc := "the canonical combining class (UnicodeData.txt) of a codepoint."
+		if err := emitTrie(os.Stdout, *format, *pkg, *name, doc, trie); err != nil {
+			panic(err)
+		}
+	default:
+		panic(fmt.Errorf("%v: unknown table layout (must be array or trie)", *layout))
+	}
+}
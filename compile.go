@@ -0,0 +1,117 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// planeBits is a bitset with one bit per codepoint in a plane.
+type planeBits [planeSize / 64]uint64
+
+func (_this *planeBits) set(offset rune) {
+	_this[offset/64] |= 1 << (offset % 64)
+}
+
+func (_this *planeBits) contains(offset rune) bool {
+	return _this[offset/64]&(1<<(offset%64)) != 0
+}
+
+// CompiledMatcher evaluates a matcher once per plane and remembers the result
+// as a bitset, so that every later lookup is a single bit test.
+type CompiledMatcher struct {
+	matcher   CodepointMatcher
+	planes    [planeCount]*planeBits
+	planeOnce [planeCount]sync.Once
+}
+
+func compileMatcher(matcher CodepointMatcher) *CompiledMatcher {
+	return &CompiledMatcher{matcher: matcher}
+}
+
+func (_this *CompiledMatcher) plane(plane int) *planeBits {
+	_this.planeOnce[plane].Do(func() {
+		bits := &planeBits{}
+		for i, cp := range codepointsInPlane(plane) {
+			if _this.matcher(cp) {
+				bits.set(rune(i))
+			}
+		}
+		_this.planes[plane] = bits
+	})
+	return _this.planes[plane]
+}
+
+func (_this *CompiledMatcher) Matches(cp Codepoint) bool {
+	if cp.Codepoint < 0 || cp.Codepoint > maxCodepoint {
+		return false
+	}
+	return _this.plane(int(cp.Codepoint / planeSize)).contains(cp.Codepoint % planeSize)
+}
+
+var compiledQueries = struct {
+	sync.Mutex
+	byQuery map[string]*CompiledMatcher
+}{byQuery: map[string]*CompiledMatcher{}}
+
+// normalizeQuery reduces a query to a canonical form. A list of search params
+// is OR'd together, so their order and any duplicates don't matter. An
+// expression only gets its whitespace normalized.
+func normalizeQuery(query string) string {
+	if isQueryExpression(query) {
+		return strings.Join(strings.Fields(query), " ")
+	}
+	params := strings.Fields(query)
+	sort.Strings(params)
+	unique := params[:0]
+	for i, param := range params {
+		if i == 0 || param != params[i-1] {
+			unique = append(unique, param)
+		}
+	}
+	return strings.Join(unique, " ")
+}
+
+// compileQuery parses and compiles a query, or returns the already compiled
+// matcher if an equivalent query has been seen before.
+func compileQuery(query string) *CompiledMatcher {
+	key := normalizeQuery(query)
+
+	compiledQueries.Lock()
+	defer compiledQueries.Unlock()
+	if compiled, ok := compiledQueries.byQuery[key]; ok {
+		return compiled
+	}
+
+	matchers := parseMatchers(key)
+	compiled := compileMatcher(func(cp Codepoint) bool {
+		for _, matcher := range matchers {
+			if matcher(cp) {
+				return true
+			}
+		}
+		return false
+	})
+	compiledQueries.byQuery[key] = compiled
+	return compiled
+}
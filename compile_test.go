@@ -0,0 +1,82 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"testing"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		expected string
+	}{
+		{"cat=Lu", "cat=Lu"},
+		{"cat=Lu cat=Ll", "cat=Ll cat=Lu"},
+		{"  cat=Ll\tcat=Lu  ", "cat=Ll cat=Lu"},
+		{"cat=Lu cat=Ll cat=Lu cat=Ll", "cat=Ll cat=Lu"},
+		{"cat=L  &  !cat=Lu", "cat=L & !cat=Lu"},
+		{"!cat=Lu & cat=L", "!cat=Lu & cat=L"},
+		{"cat=L !ch=a-f", "cat=L !ch=a-f"},
+		{"cat=Lu | cat=Ll | cat=Lu", "cat=Lu | cat=Ll | cat=Lu"},
+	} {
+		if actual := normalizeQuery(test.query); actual != test.expected {
+			t.Errorf("%q: expected %q but got %q", test.query, test.expected, actual)
+		}
+	}
+}
+
+func TestCompileQueryCache(t *testing.T) {
+	first := compileQuery("cat=Lu cat=Nd")
+	for _, query := range []string{"cat=Nd cat=Lu", " cat=Lu  cat=Nd ", "cat=Nd cat=Lu cat=Nd"} {
+		if compileQuery(query) != first {
+			t.Errorf("%q: didn't share the compiled matcher of %q", query, "cat=Lu cat=Nd")
+		}
+	}
+
+	// Operands of an expression aren't reordered, even when it wouldn't
+	// change what matches.
+	expression := compileQuery("cat=L & !cat=Lu")
+	for _, query := range []string{"!cat=Lu & cat=L", "cat=L | !cat=Lu", "cat=L !cat=Lu"} {
+		if compileQuery(query) == expression {
+			t.Errorf("%q: shared the compiled matcher of %q", query, "cat=L & !cat=Lu")
+		}
+	}
+	if compileQuery("cat=L  &  !cat=Lu") != expression {
+		t.Errorf("whitespace changes didn't share a compiled matcher")
+	}
+	if compileQuery("cat=Lu | cat=Nd") == first {
+		t.Errorf("an expression shared the compiled matcher of a param list")
+	}
+
+	// A new table throws away everything compiled from the old one.
+	useTestTables(t, "gc")
+	second := compileQuery("cat=Lu cat=Nd")
+	if second == first {
+		t.Errorf("useTables didn't invalidate the compiled matcher")
+	}
+	if !second.Matches(getCodepoint('A')) || !second.Matches(getCodepoint('0')) || second.Matches(getCodepoint('a')) {
+		t.Errorf("recompiled matcher gave the wrong result")
+	}
+	if compileQuery("cat=Nd cat=Lu") != second {
+		t.Errorf("useTables stopped new queries from being cached")
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	subcommands["convert"] = subcommand{
+		description: "Re-emit a saved result (JSON, JSON Lines, CSV, or text) in another output format",
+		run:         runConvert,
+	}
+	inputFormats["json"] = inputFormat{"A JSON array of [first, last] pairs or {\"begin\", \"end\"} objects, or a -events result record", parseJSONRanges}
+	inputFormats["jsonl"] = inputFormat{"JSON Lines, one {\"begin\", \"end\"} object per line (-format jsonl)", parseJSONLRanges}
+	inputFormats["csv"] = inputFormat{"CSV with a first and (optional) last codepoint per row, in hex", parseCSVRanges}
+	inputFormats["text"] = inputFormat{"Codepoints and ranges in any notation, e.g. 0041..005A or [#x41-#x5A] (anything after ; or a # comment is ignored)", parseTextRanges}
+}
+
+// jsonRangeValue is one range in a JSON file, which can either be a [first,
+// last] pair or an object like the ones -format jsonl writes.
+type jsonRangeValue struct {
+	Range
+}
+
+func (_this *jsonRangeValue) UnmarshalJSON(data []byte) error {
+	var pair []rune
+	if err := json.Unmarshal(data, &pair); err == nil {
+		switch len(pair) {
+		case 1:
+			_this.Begin, _this.End = pair[0], pair[0]
+		case 2:
+			_this.Begin, _this.End = pair[0], pair[1]
+		default:
+			return fmt.Errorf("%s: ranges must have one or two codepoints", data)
+		}
+		return nil
+	}
+	var object jsonlRange
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("%s: not a range", data)
+	}
+	_this.Begin, _this.End = object.Begin, object.End
+	return nil
+}
+
+// addParsedRange checks r and appends it to ranges, which get normalized once
+// they've all been read.
+func addParsedRange(ranges Ranges, r Range) (Ranges, error) {
+	if r.Begin < 0 || r.End > maxCodepoint || r.End < r.Begin {
+		return nil, fmt.Errorf("%v: invalid range", r.String())
+	}
+	return append(ranges, r), nil
+}
+
+func parseJSONRanges(source []byte, opts *InputOptions) (ranges Ranges, err error) {
+	var values []jsonRangeValue
+	if bytes.HasPrefix(bytes.TrimSpace(source), []byte("{")) {
+		var record struct {
+			Ranges []jsonRangeValue `json:"ranges"`
+		}
+		err = json.Unmarshal(source, &record)
+		values = record.Ranges
+	} else {
+		err = json.Unmarshal(source, &values)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		if ranges, err = addParsedRange(ranges, value.Range); err != nil {
+			return nil, err
+		}
+	}
+	return normalizeRanges(ranges), nil
+}
+
+func parseJSONLRanges(source []byte, opts *InputOptions) (ranges Ranges, err error) {
+	for i, line := range strings.Split(string(source), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var value jsonRangeValue
+		if err := json.Unmarshal([]byte(line), &value); err != nil {
+			return nil, fmt.Errorf("line %v: %w", i+1, err)
+		}
+		if ranges, err = addParsedRange(ranges, value.Range); err != nil {
+			return nil, fmt.Errorf("line %v: %w", i+1, err)
+		}
+	}
+	return normalizeRanges(ranges), nil
+}
+
+func parseCSVRanges(source []byte, opts *InputOptions) (ranges Ranges, err error) {
+	reader := csv.NewReader(bytes.NewReader(source))
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return normalizeRanges(ranges), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		begin, err := parseCodepointNotation(strings.TrimSpace(record[0]))
+		if err != nil {
+			if row == 1 {
+				// Header row
+				continue
+			}
+			return nil, fmt.Errorf("row %v: %w", row, err)
+		}
+		end := begin
+		if len(record) > 1 && strings.TrimSpace(record[1]) != "" {
+			if end, err = parseCodepointNotation(strings.TrimSpace(record[1])); err != nil {
+				return nil, fmt.Errorf("row %v: %w", row, err)
+			}
+		}
+		if ranges, err = addParsedRange(ranges, Range{Begin: begin, End: end}); err != nil {
+			return nil, fmt.Errorf("row %v: %w", row, err)
+		}
+	}
+}
+
+// textComment returns where the comment starts in a line of a text file, or
+// -1 if it has none. Comments start at ; or #, except for the # of an EBNF
+// #x codepoint, so that the default output format can be read back.
+func textComment(line string) int {
+	for i := 0; i < len(line); i++ {
+		if line[i] == ';' || (line[i] == '#' && !strings.HasPrefix(line[i:], "#x")) {
+			return i
+		}
+	}
+	return -1
+}
+
+func parseTextRanges(source []byte, opts *InputOptions) (ranges Ranges, err error) {
+	for i, line := range strings.Split(string(source), "\n") {
+		if j := textComment(line); j >= 0 {
+			line = line[:j]
+		}
+		lineRanges, err := parseRangeList(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %w", i+1, err)
+		}
+		ranges = append(ranges, lineRanges...)
+	}
+	return normalizeRanges(ranges), nil
+}
+
+// inputFormatOf guesses the format of a saved result from its file name.
+func inputFormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".jsonl", ".ndjson":
+		return "jsonl"
+	case ".csv":
+		return "csv"
+	}
+	return "text"
+}
+
+func runConvert(args []string) {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	in := flags.String("in", "-", "Saved result to read (- for stdin)")
+	inFormat := flags.String("in-format", "", "Format of the saved result (default: guessed from the file extension, otherwise text)")
+	format := flags.String("format", "ebnf", "Output format")
+	leadup := flags.String("leadup", "", "Leadup text to print and align to")
+	highCol := flags.Int("highcol", 80, "Highest column to print at (columns start at 1)")
+	name := flags.String("name", "", "Name of the generated set or function, for formats that generate code")
+	style := flags.String("style", "", "Kind of code to generate, for formats that support more than one")
+	pkg := flags.String("package", "", "Package of generated Go code (default main)")
+	flags.Usage = func() {
+		fmt.Println("Usage: unicode_range_finder convert -in <file> -format <output format> [options]")
+		fmt.Println("\nThe saved result can be in any of these formats:")
+		for _, name := range inputFormatNames() {
+			fmt.Printf("    %v: %v\n", name, inputFormats[name].description)
+		}
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if *inFormat == "" {
+		*inFormat = inputFormatOf(*in)
+	}
+	ranges, err := parseRangesFile(*in, *inFormat, &InputOptions{})
+	if err != nil {
+		panic(err)
+	}
+	opts := &OutputOptions{Leadup: *leadup, HighCol: *highCol, Name: *name, Style: *style, Package: *pkg}
+	if err := emitRanges(os.Stdout, *format, ranges, opts); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestConvertInputFormats(t *testing.T) {
+	for _, test := range []struct {
+		format   string
+		source   string
+		expected string
+	}{
+		{"json", `[[97, 122], [65, 90], [95]]`, "41-5a 5f 61-7a"},
+		{"json", `[{"begin": 65, "end": 90}, {"begin": 80, "end": 97, "count": 18}]`, "41-61"},
+		{"json", `{"query": "cat=Lu", "ranges": [{"begin": 65, "end": 90}]}`, "41-5a"},
+		{"json", `[]`, ""},
+		{"jsonl", "{\"begin\":97,\"end\":122}\n\n{\"begin\":65,\"end\":90,\"blocks\":[\"ASCII\"]}\n", "41-5a 61-7a"},
+		{"csv", "first,last\n0061,007A\n41,5A\n# a comment\nU+005F\n", "41-5a 5f 61-7a"},
+		{"csv", "0061,007A\n0041,\n", "41 61-7a"},
+		{"text", "0061..007A ; Latin\n0041-005A # letters\n\n[#x5F]\n", "41-5a 5f 61-7a"},
+	} {
+		ranges, err := inputFormats[test.format].parse([]byte(test.source), &InputOptions{})
+		if err != nil {
+			t.Errorf("%v [%v]: %v", test.format, test.source, err)
+			continue
+		}
+		if expected := rangesOf(test.expected); !reflect.DeepEqual(ranges, expected) {
+			t.Errorf("%v [%v]: expected %v but got %v", test.format, test.source, expected, ranges)
+		}
+	}
+}
+
+func TestConvertInputFormatErrors(t *testing.T) {
+	for _, test := range []struct {
+		format string
+		source string
+	}{
+		{"json", `[[90, 65]]`},
+		{"json", `[[1, 2, 3]]`},
+		{"json", `[[-1, 5]]`},
+		{"json", `[[65, 1114112]]`},
+		{"json", `["A"]`},
+		{"json", `{"ranges": 5}`},
+		{"jsonl", "{\"begin\":65,\"end\":90}\nnot json\n"},
+		{"jsonl", "{\"begin\":90,\"end\":65}\n"},
+		{"csv", "first,last\n0041,zz\n"},
+		{"csv", "0041,0040\n"},
+		{"text", "0041..zz\n"},
+	} {
+		if _, err := inputFormats[test.format].parse([]byte(test.source), &InputOptions{}); err == nil {
+			t.Errorf("%v [%v]: expected an error", test.format, test.source)
+		}
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		output        string
+		rangeMetadata bool
+		input         string
+	}{
+		{"json", false, "json"},
+		{"json", true, "json"},
+		{"jsonl", false, "jsonl"},
+		{"jsonl", true, "jsonl"},
+		{"ebnf", false, "text"},
+	} {
+		opts := goldenOptions
+		opts.RangeMetadata = test.rangeMetadata
+		var source bytes.Buffer
+		if err := emitRanges(&source, test.output, goldenRanges, &opts); err != nil {
+			t.Errorf("%v: %v", test.output, err)
+			continue
+		}
+		ranges, err := inputFormats[test.input].parse(source.Bytes(), &InputOptions{})
+		if err != nil {
+			t.Errorf("%v: %v\n%s", test.output, err, source.Bytes())
+			continue
+		}
+		if !reflect.DeepEqual(ranges, goldenRanges) {
+			t.Errorf("%v: expected %v but got %v\n%s", test.output, goldenRanges, ranges, source.Bytes())
+		}
+	}
+}
+
+// TestConvertRegexJS runs the example from the convert request.
+func TestConvertRegexJS(t *testing.T) {
+	in := writeTestFile(t, t.TempDir(), "ranges.jsonl", "{\"begin\":65,\"end\":90}\n{\"begin\":128512,\"end\":128512}\n")
+	stdout, stderr, err := runMain(t, nil, "convert", "-in", in, "-format", "regex-js")
+	if err != nil {
+		t.Fatalf("%v: %v", err, stderr)
+	}
+	if expected := "[\\u{41}-\\u{5A}\\u{1F600}]\n"; stdout != expected {
+		t.Errorf("expected %q but got %q", expected, stdout)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+func init() {
+	subcommands["profile"] = subcommand{
+		description: "Report the scripts, blocks, and categories used across a directory of text files, and which files break a policy",
+		run:         runProfile,
+	}
+}
+
+// FileProfile counts the codepoints in one file.
+type FileProfile struct {
+	Path   string
+	Counts map[rune]int
+	// InvalidBytes is the number of bytes that aren't valid UTF-8.
+	InvalidBytes int
+	Err          error
+}
+
+func profileFile(path string) *FileProfile {
+	profile := &FileProfile{Path: path, Counts: map[rune]int{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		profile.Err = err
+		return profile
+	}
+	for len(data) > 0 {
+		cp, size := utf8.DecodeRune(data)
+		if cp == utf8.RuneError && size <= 1 {
+			profile.InvalidBytes++
+		} else {
+			profile.Counts[cp]++
+		}
+		data = data[size:]
+	}
+	return profile
+}
+
+// corpusFiles lists the regular files under dir, skipping hidden directories
+// (such as .git). If extensions isn't empty, only files with one of them are
+// included.
+func corpusFiles(dir string, extensions []string) (paths []string, err error) {
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if len(extensions) > 0 && !containsString(extensions, filepath.Ext(path)) {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	return
+}
+
+func containsString(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// profileCorpus profiles every file with a pool of workers. The profiles are
+// returned in the same order as paths.
+func profileCorpus(paths []string, workers int) []*FileProfile {
+	profiles := make([]*FileProfile, len(paths))
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				profiles[index] = profileFile(paths[index])
+			}
+		}()
+	}
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return profiles
+}
+
+// CorpusGroup is one line of a per-script, per-block, or per-category
+// breakdown.
+type CorpusGroup struct {
+	Name       string
+	Characters int
+	Distinct   int
+}
+
+// groupCounts totals counts by a group key (see groupKeys), most used first.
+func groupCounts(counts map[rune]int, by string) (groups []CorpusGroup) {
+	key := groupKeys[by]
+	indexes := map[string]int{}
+	for cp, count := range counts {
+		name := key(getCodepoint(cp))
+		index, ok := indexes[name]
+		if !ok {
+			index = len(groups)
+			indexes[name] = index
+			groups = append(groups, CorpusGroup{Name: name})
+		}
+		groups[index].Characters += count
+		groups[index].Distinct++
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Characters != groups[j].Characters {
+			return groups[i].Characters > groups[j].Characters
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return
+}
+
+func sortedCodepoints(counts map[rune]int) (codepoints []rune) {
+	for cp := range counts {
+		codepoints = append(codepoints, cp)
+	}
+	sort.Slice(codepoints, func(i, j int) bool { return codepoints[i] < codepoints[j] })
+	return
+}
+
+func runProfile(args []string) {
+	flags := flag.NewFlagSet("profile", flag.ExitOnError)
+	dir := flags.String("dir", "", "Directory of text files to profile")
+	extensions := flags.String("ext", "", "Only profile files with these extensions (comma separated, e.g. .txt,.md)")
+	policy := flags.String("policy", "", "Search params for the characters the files are allowed to use")
+	policyFile := flags.String("policy-file", "", "Query file for the characters the files are allowed to use")
+	rare := flags.Int("rare", 1, "Report codepoints that occur this many times or fewer across the corpus (0 = don't report)")
+	workers := flags.Int("workers", runtime.NumCPU(), "Number of files to read at once")
+	flags.Usage = func() {
+		fmt.Println("Usage: unicode_range_finder profile -dir <directory> [options]")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+	if *dir == "" || flags.NArg() > 0 || *workers < 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	var allowed *CompiledMatcher
+	policyQuery := *policy
+	if *policyFile != "" {
+		q, err := loadQueryFile(*policyFile)
+		if err != nil {
+			panic(err)
+		}
+		policyQuery += " " + q
+	}
+	if strings.TrimSpace(policyQuery) != "" {
+		allowed = compileQuery(policyQuery)
+	}
+
+	var extensionList []string
+	if *extensions != "" {
+		extensionList = splitList(*extensions)
+	}
+	paths, err := corpusFiles(*dir, extensionList)
+	if err != nil {
+		panic(err)
+	}
+
+	totals := map[rune]int{}
+	firstFile := map[rune]string{}
+	characters := 0
+	var unreadable, invalid []*FileProfile
+	type violation struct {
+		path       string
+		characters int
+		codepoints []rune
+	}
+	var violations []violation
+	for _, profile := range profileCorpus(paths, *workers) {
+		if profile.Err != nil {
+			unreadable = append(unreadable, profile)
+			continue
+		}
+		if profile.InvalidBytes > 0 {
+			invalid = append(invalid, profile)
+		}
+		v := violation{path: profile.Path}
+		for _, cp := range sortedCodepoints(profile.Counts) {
+			count := profile.Counts[cp]
+			totals[cp] += count
+			characters += count
+			if _, ok := firstFile[cp]; !ok {
+				firstFile[cp] = profile.Path
+			}
+			if allowed != nil && !allowed.Matches(getCodepoint(cp)) {
+				v.characters += count
+				v.codepoints = append(v.codepoints, cp)
+			}
+		}
+		if v.characters > 0 {
+			violations = append(violations, v)
+		}
+	}
+
+	fmt.Printf("Files: %v\n", len(paths)-len(unreadable))
+	fmt.Printf("Characters: %v (%v distinct codepoints)\n", characters, len(totals))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, by := range []string{"script", "block", "category"} {
+		fmt.Fprintf(tw, "\n%v\tCHARACTERS\tDISTINCT\tSHARE\n", strings.ToUpper(by))
+		for _, group := range groupCounts(totals, by) {
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%.2f%%\n", group.Name, group.Characters, group.Distinct, 100*float64(group.Characters)/float64(characters))
+		}
+	}
+	tw.Flush()
+
+	if *rare > 0 {
+		fmt.Printf("\nRare codepoints (%v occurrences or fewer):\n", *rare)
+		fmt.Fprintln(tw, "CODEPOINT\tCHAR\tCOUNT\tNAME\tFIRST FILE")
+		for _, cp := range sortedCodepoints(totals) {
+			if totals[cp] > *rare {
+				continue
+			}
+			codepoint := getCodepoint(cp)
+			fmt.Fprintf(tw, "U+%04X\t%v\t%v\t%v\t%v\n", cp, safeGlyph(&codepoint), totals[cp], codepoint.Name, firstFile[cp])
+		}
+		tw.Flush()
+	}
+
+	if allowed != nil {
+		fmt.Printf("\nFiles with characters outside the policy: %v\n", len(violations))
+		if len(violations) > 0 {
+			fmt.Fprintln(tw, "FILE\tCHARACTERS\tCODEPOINTS")
+			for _, v := range violations {
+				fmt.Fprintf(tw, "%v\t%v\t%v\n", v.path, v.characters, codepointList(v.codepoints))
+			}
+			tw.Flush()
+		}
+	}
+
+	for _, profile := range invalid {
+		reportWarning("%v: skipped %v bytes that aren't valid UTF-8", profile.Path, profile.InvalidBytes)
+	}
+	for _, profile := range unreadable {
+		reportWarning("%v", profile.Err)
+	}
+	if len(violations) > 0 {
+		os.Exit(1)
+	}
+}
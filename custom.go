@@ -0,0 +1,79 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Matcher is a predicate that a program embedding the finder can register
+// with RegisterMatcher, which queries can then use as custom:<name> alongside
+// the built in search params (e.g. "cat=L custom:myfont").
+type Matcher interface {
+	Matches(cp Codepoint) bool
+}
+
+// MatcherFunc lets an ordinary function be used as a Matcher.
+type MatcherFunc func(cp Codepoint) bool
+
+func (_this MatcherFunc) Matches(cp Codepoint) bool {
+	return _this(cp)
+}
+
+type customMatcher struct {
+	description string
+	matcher     Matcher
+}
+
+var customMatchers = map[string]customMatcher{}
+
+// RegisterMatcher makes matcher available to queries as custom:<name>. It
+// must be called before any query that uses it runs, since compiled queries
+// are kept for reuse. Names can't be registered twice.
+func RegisterMatcher(name string, description string, matcher Matcher) error {
+	if name == "" || strings.ContainsAny(name, " \t\n=") {
+		return fmt.Errorf("%q: custom matcher names can't be empty or contain spaces or =", name)
+	}
+	if _, ok := customMatchers[name]; ok {
+		return fmt.Errorf("custom:%v: already registered", name)
+	}
+	customMatchers[name] = customMatcher{description: description, matcher: matcher}
+	return nil
+}
+
+func customMatcherNames() (names []string) {
+	for name := range customMatchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// findCustomMatcher looks up a custom:<name> search param.
+func findCustomMatcher(param string) (CodepointMatcher, bool) {
+	custom, ok := customMatchers[strings.TrimPrefix(param, "custom:")]
+	if !ok {
+		return nil, false
+	}
+	return custom.matcher.Matches, true
+}
@@ -0,0 +1,181 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// The codepoint table can live in an external data file rather than in
+// generated.go, so that a binary built with the nodata tag can be shipped
+// without the table, and the table updated without rebuilding the binary.
+// The file is plain text:
+//
+//	unicode_range_finder data 1
+//	unicode 14.0.0
+//	sha256 <hash of everything after the blank line>
+//
+//	attribute gc
+//	value Cc
+//	value Zs
+//	...
+//	plane <runs for plane 0>
+//	...
+//	plane <runs for plane 16>
+//	attribute ...
+//
+// where the values and planes are the same as in generated.go. The file is
+// memory mapped, and the decoded tables point straight into the mapping, so
+// loading it costs little more than checking the hash.
+const (
+	dataFileMagic   = "unicode_range_finder data"
+	dataFileVersion = 1
+	dataFileEnv     = "UNICODE_RANGE_FINDER_DATA"
+)
+
+// loadDataFile replaces the built in tables with the ones in a data file. It
+// has to be called before anything gets decoded.
+func loadDataFile(path string) error {
+	data, err := mapFile(path)
+	if err != nil {
+		return err
+	}
+	// The mapping is never unmapped, so the strings can share its memory.
+	version, tables, checksum, err := parseDataFile(*(*string)(unsafe.Pointer(&data)))
+	if err != nil {
+		return fmt.Errorf("%v: %w", path, err)
+	}
+	generatedUnicodeVersion = version
+	generatedTables = tables
+	generatedChecksum = checksum
+	return nil
+}
+
+func parseDataFile(contents string) (version string, tables map[string]*generatedTable, checksum string, err error) {
+	header, payload, ok := strings.Cut(contents, "\n\n")
+	if !ok {
+		return "", nil, "", fmt.Errorf("not a data file (missing header)")
+	}
+	headerLines := strings.Split(header, "\n")
+	if len(headerLines) != 3 || !strings.HasPrefix(headerLines[0], dataFileMagic+" ") {
+		return "", nil, "", fmt.Errorf("not a data file (bad header)")
+	}
+	if formatVersion := strings.TrimPrefix(headerLines[0], dataFileMagic+" "); formatVersion != fmt.Sprint(dataFileVersion) {
+		return "", nil, "", fmt.Errorf("data file format %v isn't supported (expected %v)", formatVersion, dataFileVersion)
+	}
+	version, ok = cutPrefix(headerLines[1], "unicode ")
+	if !ok {
+		return "", nil, "", fmt.Errorf("data file is missing its Unicode version")
+	}
+	expected, ok := cutPrefix(headerLines[2], "sha256 ")
+	if !ok {
+		return "", nil, "", fmt.Errorf("data file is missing its checksum")
+	}
+	hash := sha256.Sum256([]byte(payload))
+	checksum = hex.EncodeToString(hash[:])
+	if checksum != expected {
+		return "", nil, "", fmt.Errorf("data file is corrupt (checksum mismatch)")
+	}
+
+	tables = map[string]*generatedTable{}
+	var table *generatedTable
+	plane := 0
+	for lineNumber := len(headerLines) + 2; payload != ""; lineNumber++ {
+		var line string
+		line, payload, _ = strings.Cut(payload, "\n")
+		key, value, _ := strings.Cut(line, " ")
+		switch {
+		case key == "attribute":
+			if table != nil && plane != planeCount {
+				return "", nil, "", fmt.Errorf("line %v: previous attribute has %v planes (expected %v)", lineNumber, plane, planeCount)
+			}
+			table = &generatedTable{}
+			tables[value] = table
+			plane = 0
+		case table == nil:
+			return "", nil, "", fmt.Errorf("line %v: expected an attribute", lineNumber)
+		case key == "value":
+			table.Values = append(table.Values, value)
+		case key == "plane" && plane < planeCount:
+			table.Planes[plane] = value
+			plane++
+		default:
+			return "", nil, "", fmt.Errorf("line %v: unexpected [%v]", lineNumber, line)
+		}
+	}
+	if table != nil && plane != planeCount {
+		return "", nil, "", fmt.Errorf("last attribute has %v planes (expected %v)", plane, planeCount)
+	}
+	return version, tables, checksum, nil
+}
+
+// cutPrefix is strings.CutPrefix, which needs a newer Go.
+func cutPrefix(s string, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// encodeTables writes tables in the form used after the header of a data
+// file. The SHA-256 of the result is the checksum of the tables.
+func encodeTables(tables map[string]*generatedTable) string {
+	var attributes []string
+	for attribute := range tables {
+		attributes = append(attributes, attribute)
+	}
+	sort.Strings(attributes)
+
+	payload := strings.Builder{}
+	for _, attribute := range attributes {
+		table := tables[attribute]
+		payload.WriteString(fmt.Sprintf("attribute %v\n", attribute))
+		for _, value := range table.Values {
+			payload.WriteString(fmt.Sprintf("value %v\n", value))
+		}
+		for _, plane := range table.Planes {
+			payload.WriteString(fmt.Sprintf("plane %v\n", plane))
+		}
+	}
+	return payload.String()
+}
+
+// tablesChecksum identifies a set of tables. The generator writes it to
+// generated.go as generatedChecksum, and a data file carries it in its
+// header, so that selftest can tell whether the tables have been damaged.
+func tablesChecksum(tables map[string]*generatedTable) string {
+	hash := sha256.Sum256([]byte(encodeTables(tables)))
+	return hex.EncodeToString(hash[:])
+}
+
+func writeDataFile(path string, version string, tables map[string]*generatedTable) error {
+	payload := encodeTables(tables)
+	hash := sha256.Sum256([]byte(payload))
+	contents := fmt.Sprintf("%v %v\nunicode %v\nsha256 %v\n\n%v",
+		dataFileMagic, dataFileVersion, version, hex.EncodeToString(hash[:]), payload)
+	return os.WriteFile(path, []byte(contents), 0644)
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// dataFileOf builds a data file around payload, with a correct checksum.
+func dataFileOf(payload string) string {
+	hash := sha256.Sum256([]byte(payload))
+	return fmt.Sprintf("%v %v\nunicode 14.0.0\nsha256 %v\n\n%v", dataFileMagic, dataFileVersion, hex.EncodeToString(hash[:]), payload)
+}
+
+// planesOf returns count plane lines that each hold one run of value 0.
+func planesOf(count int) string {
+	return strings.Repeat("plane 10000:0\n", count)
+}
+
+func testTables() map[string]*generatedTable {
+	gc := &generatedTable{Values: []string{"Cn", "Lu", "Ll"}}
+	age := &generatedTable{Values: []string{"", "1.1"}}
+	for plane := 0; plane < planeCount; plane++ {
+		gc.Planes[plane] = "10000:0"
+		age.Planes[plane] = "10000:0"
+	}
+	gc.Planes[0] = "41:0 1a:1 6:0 1a:2 ff85:0"
+	age.Planes[0] = "80:1 ff80:0"
+	return map[string]*generatedTable{"gc": gc, "age": age}
+}
+
+func TestDataFileRoundTrip(t *testing.T) {
+	tables := testTables()
+	path := filepath.Join(t.TempDir(), "test.data")
+	if err := writeDataFile(path, "14.0.0", tables); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, parsed, checksum, err := parseDataFile(string(contents))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "14.0.0" {
+		t.Errorf("expected version 14.0.0 but got %v", version)
+	}
+	if !reflect.DeepEqual(parsed, tables) {
+		t.Errorf("tables differ after a round trip: %v", parsed)
+	}
+	if expected := tablesChecksum(tables); checksum != expected {
+		t.Errorf("expected checksum %v but got %v", expected, checksum)
+	}
+}
+
+func TestLoadDataFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.data")
+	if err := writeDataFile(path, "14.0.0", testTables()); err != nil {
+		t.Fatal(err)
+	}
+	version, tables, checksum := generatedUnicodeVersion, generatedTables, generatedChecksum
+	defer func() {
+		generatedUnicodeVersion, generatedTables, generatedChecksum = version, tables, checksum
+	}()
+	if err := loadDataFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(generatedTables, testTables()) || generatedChecksum != tablesChecksum(testTables()) {
+		t.Errorf("loaded tables differ from the ones written")
+	}
+	if err := loadDataFile(filepath.Join(t.TempDir(), "missing.data")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestParseDataFileErrors(t *testing.T) {
+	valid := dataFileOf("attribute gc\nvalue Cn\n" + planesOf(planeCount))
+	for _, test := range []struct {
+		name     string
+		contents string
+		message  string
+	}{
+		{"empty", "", "missing header"},
+		{"no payload", "unicode_range_finder data 1\nunicode 14.0.0\nsha256 0\n", "missing header"},
+		{"not a data file", strings.Replace(valid, "unicode_range_finder data", "something else", 1), "bad header"},
+		{"newer format", strings.Replace(valid, "data 1\n", "data 2\n", 1), "format 2"},
+		{"no version", strings.Replace(valid, "unicode 14.0.0", "version 14.0.0", 1), "Unicode version"},
+		{"no checksum", strings.Replace(valid, "sha256 ", "md5 ", 1), "checksum"},
+		{"corrupt", strings.Replace(valid, "value Cn", "value Lu", 1), "checksum mismatch"},
+		{"value first", dataFileOf("value Cn\nattribute gc\n"), "expected an attribute"},
+		{"missing planes", dataFileOf("attribute gc\nvalue Cn\n" + planesOf(3) + "attribute age\n"), "previous attribute has 3 planes"},
+		{"last missing planes", dataFileOf("attribute gc\nvalue Cn\n" + planesOf(3)), "last attribute has 3 planes"},
+		{"extra plane", dataFileOf("attribute gc\nvalue Cn\n" + planesOf(planeCount+1)), "unexpected"},
+		{"unknown line", dataFileOf("attribute gc\nvalues Cn\n"), "unexpected [values Cn]"},
+	} {
+		_, _, _, err := parseDataFile(test.contents)
+		if err == nil {
+			t.Errorf("%v: expected an error", test.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.message) {
+			t.Errorf("%v: expected an error about [%v] but got [%v]", test.name, test.message, err)
+		}
+	}
+	if _, _, _, err := parseDataFile(valid); err != nil {
+		t.Errorf("valid file: %v", err)
+	}
+}
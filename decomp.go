@@ -0,0 +1,168 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func init() {
+	subcommands["decomp"] = subcommand{
+		description: "Generate decomposition mapping tables for the matched codepoints",
+		run:         runDecomp,
+	}
+}
+
+const (
+	hangulSBase  = 0xac00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11a7
+	hangulTCount = 28
+	hangulNCount = 21 * hangulTCount
+	hangulSCount = 19 * hangulNCount
+)
+
+func isHangulSyllable(cp rune) bool {
+	return cp >= hangulSBase && cp < hangulSBase+hangulSCount
+}
+
+// decompositionType returns the dt attribute of cp ("can" for canonical,
+// "none" if it doesn't decompose, and one of the compatibility tags such as
+// "font" or "wide" otherwise).
+func decompositionType(cp rune) string {
+	if isHangulSyllable(cp) {
+		return "can"
+	}
+	if dt := attributeValue("dt", cp); dt != "" {
+		return dt
+	}
+	return "none"
+}
+
+// decompose returns the decomposition mapping of cp, or cp itself if it
+// doesn't decompose. Hangul syllables are decomposed algorithmically (Unicode
+// 3.12). If recursive is set, the result is fully decomposed.
+func decompose(cp rune, canonicalOnly bool, recursive bool) []rune {
+	dt := decompositionType(cp)
+	if dt == "none" || (canonicalOnly && dt != "can") {
+		return []rune{cp}
+	}
+
+	var mapping []rune
+	if isHangulSyllable(cp) {
+		index := cp - hangulSBase
+		mapping = []rune{
+			hangulLBase + index/hangulNCount,
+			hangulVBase + (index%hangulNCount)/hangulTCount,
+		}
+		if t := index % hangulTCount; t != 0 {
+			mapping = append(mapping, hangulTBase+t)
+		}
+	} else {
+		mapping = codepointSequence("dm", cp)
+	}
+	if !recursive {
+		return mapping
+	}
+
+	var result []rune
+	for _, r := range mapping {
+		if r == cp {
+			result = append(result, r)
+			continue
+		}
+		result = append(result, decompose(r, canonicalOnly, true)...)
+	}
+	return result
+}
+
+// decompositionTable builds a table of every codepoint in ranges that has a
+// decomposition mapping, along with whether it is excluded from composition
+// (Full_Composition_Exclusion).
+func decompositionTable(ranges Ranges, canonicalOnly bool, recursive bool, hangul bool, name string) *MappingTable {
+	doc := "Canonical and compatibility decomposition mappings (UnicodeData.txt)"
+	if canonicalOnly {
+		doc = "Canonical decomposition mappings (UnicodeData.txt)"
+	}
+	if recursive {
+		doc += ", fully decomposed"
+	}
+	doc += ". Excluded marks Full_Composition_Exclusion."
+	if !hangul {
+		doc += " Hangul syllables are left out, and must be decomposed algorithmically."
+	}
+
+	table := &MappingTable{
+		Name: name,
+		Doc:  doc,
+		Columns: []MappingColumn{
+			{Name: "Type", Kind: ColumnString},
+			{Name: "Mapping", Kind: ColumnRunes},
+			{Name: "Excluded", Kind: ColumnBool},
+		},
+	}
+	excluded := binaryPropertyIndex("Comp_Ex")
+	for _, r := range ranges {
+		for cp := r.Begin; cp <= r.End; cp++ {
+			if isHangulSyllable(cp) && !hangul {
+				continue
+			}
+			dt := decompositionType(cp)
+			if dt == "none" || (canonicalOnly && dt != "can") {
+				continue
+			}
+			table.Rows = append(table.Rows, MappingRow{
+				Codepoint: cp,
+				Values: []interface{}{
+					dt,
+					decompose(cp, canonicalOnly, recursive),
+					getCodepoint(cp).Properties.Has(excluded),
+				},
+			})
+		}
+	}
+	return table
+}
+
+func runDecomp(args []string) {
+	flags := flag.NewFlagSet("decomp", flag.ExitOnError)
+	format := flags.String("format", "go", "Table format: go, c, or json")
+	canonical := flags.Bool("canonical", false, "Only include canonical decompositions (enough for NFC and NFD)")
+	recursive := flags.Bool("recursive", false, "Write fully decomposed mappings instead of single step ones")
+	hangul := flags.Bool("hangul", false, "Also include the Hangul syllables, which are normally decomposed algorithmically")
+	pkg := flags.String("package", "main", "Package of the generated Go file")
+	name := flags.String("name", "Decompositions", "Name of the generated table")
+	flags.Usage = func() {
+		fmt.Println("Usage: unicode_range_finder decomp [options] [search params]")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+	requireAttributes("decomp", "dt", "dm", "Comp_Ex")
+
+	table := decompositionTable(subcommandRanges(flags.Args()), *canonical, *recursive, *hangul, *name)
+	if err := emitMappingTable(os.Stdout, *format, *pkg, table); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func init() {
+	subcommands["diff"] = subcommand{
+		description: "Report the codepoints that enter or leave a query's result between two copies of the Unicode data",
+		run:         runDiff,
+	}
+}
+
+// unicodeTables is a complete codepoint table, for switching between with
+// useTables.
+type unicodeTables struct {
+	version  string
+	tables   map[string]*generatedTable
+	checksum string
+}
+
+func activeTables() *unicodeTables {
+	return &unicodeTables{version: generatedUnicodeVersion, tables: generatedTables, checksum: generatedChecksum}
+}
+
+func (_this *unicodeTables) use() {
+	useTables(_this.version, _this.tables, _this.checksum)
+}
+
+// loadUnicodeTables loads a codepoint table from a data file (see
+// -write-data), a copy of the UCD (see loadUnicodeSource), or a Unicode
+// version to fetch from unicode.org (see fetchUCD). "embedded" is the table
+// that was active when the program started.
+func loadUnicodeTables(source string, unicodeFormat string, embedded *unicodeTables) (*unicodeTables, error) {
+	if source == "embedded" {
+		return embedded, nil
+	}
+	path := source
+	if _, err := os.Stat(source); err != nil {
+		if _, versionErr := parseUnicodeVersion(source); versionErr != nil && source != "latest" {
+			return nil, err
+		}
+		if path, err = fetchUCD(source); err != nil {
+			return nil, err
+		}
+	} else if isDataFile(source) {
+		contents, err := os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		version, tables, checksum, err := parseDataFile(string(contents))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", source, err)
+		}
+		return &unicodeTables{version: version, tables: tables, checksum: checksum}, nil
+	}
+	version, tables, err := buildTables(path, unicodeFormat, 0, maxCodepoint)
+	if err != nil {
+		return nil, err
+	}
+	return &unicodeTables{version: version, tables: tables, checksum: tablesChecksum(tables)}, nil
+}
+
+// isDataFile returns true if path is a file that starts like a data file.
+func isDataFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	magic := make([]byte, len(dataFileMagic))
+	n, _ := file.Read(magic)
+	return string(magic[:n]) == dataFileMagic
+}
+
+// diffReasons are the explanations that diff gives for a codepoint entering
+// or leaving a result, in the order they're checked and reported.
+var diffReasons = []string{
+	"newly assigned in a new block",
+	"newly assigned",
+	"no longer assigned",
+	"category changed",
+	"script changed",
+	"block changed",
+	"other properties changed",
+}
+
+// diffReason explains why a codepoint entered or left a result between two
+// versions of its record.
+func diffReason(before *Codepoint, after *Codepoint) string {
+	wasAssigned := assignmentStatus(before) == "assigned"
+	isAssigned := assignmentStatus(after) == "assigned"
+	switch {
+	case !wasAssigned && isAssigned && isNoBlock(before.Block) && !isNoBlock(after.Block):
+		return diffReasons[0]
+	case !wasAssigned && isAssigned:
+		return diffReasons[1]
+	case wasAssigned && !isAssigned:
+		return diffReasons[2]
+	case before.Category() != after.Category():
+		return diffReasons[3]
+	case before.Script != after.Script:
+		return diffReasons[4]
+	case before.Block != after.Block:
+		return diffReasons[5]
+	}
+	return diffReasons[6]
+}
+
+// isNoBlock returns true for the block value of codepoints outside of any
+// block, which the UCD calls No_Block (NB) and the table leaves empty for
+// codepoints that the UCD doesn't list at all.
+func isNoBlock(block string) bool {
+	return block == "" || block == "NB"
+}
+
+// recordsOf collects the records of the codepoints in ranges from the active
+// table.
+func recordsOf(ranges Ranges) map[rune]Codepoint {
+	records := map[rune]Codepoint{}
+	for _, r := range ranges {
+		for cp := r.Begin; cp <= r.End; cp++ {
+			records[cp] = getCodepoint(cp)
+		}
+	}
+	return records
+}
+
+func runDiff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	queryPath := flags.String("query", "", "Query file to check (search params can also be given as arguments)")
+	unicodeFormat := flags.String("unicode-format", "", "Format of UCD sources: xml or txt (see -unicode-format in the main usage)")
+	format := flags.String("format", "ebnf", "Output format for the added and removed codepoints")
+	flags.Usage = func() {
+		fmt.Println("Usage: unicode_range_finder diff [options] <old> <new> [search params]")
+		fmt.Println("\nEach of old and new is one of:")
+		fmt.Println(" * A data file written with -write-data")
+		fmt.Println(" * A copy of the UCD: ucd.all.flat.xml, ucd.all.flat.zip, or a directory of UCD text files")
+		fmt.Println(" * A Unicode version to download (or take from the cache), like -fetch -univer (e.g. 15.1.0)")
+		fmt.Println(" * embedded, for the table built into the program (or loaded with -data)")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+
+	params := flags.Args()
+	if len(params) < 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	sources := params[:2]
+	params = params[2:]
+	if *queryPath != "" {
+		q, err := loadQueryFile(*queryPath)
+		if err != nil {
+			panic(err)
+		}
+		params = append(params, q)
+	}
+	if len(params) == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	q := strings.Join(params, " ")
+
+	embedded := activeTables()
+	var versions [2]*unicodeTables
+	for i, source := range sources {
+		tables, err := loadUnicodeTables(source, *unicodeFormat, embedded)
+		if err != nil {
+			panic(err)
+		}
+		versions[i] = tables
+	}
+	older, newer := versions[0], versions[1]
+
+	older.use()
+	before := searchRanges(q, 0, maxCodepoint)
+	newer.use()
+	after := searchRanges(q, 0, maxCodepoint)
+	added := subtractRanges(after, before)
+	removed := subtractRanges(before, after)
+	changed := unionRanges(added, removed)
+	afterRecords := recordsOf(changed)
+	older.use()
+	beforeRecords := recordsOf(changed)
+
+	opts := &OutputOptions{HighCol: 80}
+	for _, section := range []struct {
+		title  string
+		ranges Ranges
+	}{
+		{"Added", added},
+		{"Removed", removed},
+	} {
+		fmt.Printf("# %v (%v codepoints) from %v to %v\n", section.title, section.ranges.CodepointCount(), older.version, newer.version)
+		byReason := map[string]Ranges{}
+		for _, r := range section.ranges {
+			for cp := r.Begin; cp <= r.End; cp++ {
+				beforeRecord, afterRecord := beforeRecords[cp], afterRecords[cp]
+				reason := diffReason(&beforeRecord, &afterRecord)
+				byReason[reason] = byReason[reason].Add(cp)
+			}
+		}
+		for _, reason := range diffReasons {
+			ranges := byReason[reason]
+			if len(ranges) == 0 {
+				continue
+			}
+			fmt.Printf("## %v (%v codepoints)\n", reason, ranges.CodepointCount())
+			if err := emitRanges(os.Stdout, *format, ranges, opts); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func init() {
+	subcommands["digits"] = subcommand{
+		description: "Generate a table mapping every decimal digit (Nd) to its value, script, and zero digit",
+		run:         runDigits,
+	}
+}
+
+// digitValue returns the value of a decimal digit. Decimal digits always come
+// in runs of ten from zero to nine (UAX #44), so if the embedded data has no
+// numeric values, the value is the digit's place in its run of Nd codepoints.
+func digitValue(cp rune) int {
+	if numericType, numerator, _, ok := numericValue(cp); ok && numericType == "De" {
+		return int(numerator)
+	}
+	first := cp
+	for first > 0 {
+		previous := getCodepoint(first - 1)
+		if previous.Category() != "Nd" {
+			break
+		}
+		first--
+	}
+	return int(cp-first) % 10
+}
+
+// digitTable builds a table of the decimal digits in ranges, with the zero
+// digit of each one's run, so that converting a digit to ASCII is
+// '0' + (cp - Zero).
+func digitTable(ranges Ranges, name string) *MappingTable {
+	table := &MappingTable{
+		Name: name,
+		Doc:  "Decimal digits, with their value and the zero digit of the run they're in.",
+		Columns: []MappingColumn{
+			{Name: "Value", Kind: ColumnInt},
+			{Name: "Zero", Kind: ColumnRune},
+			{Name: "Script", Kind: ColumnString},
+		},
+	}
+	for _, r := range ranges {
+		for cp := r.Begin; cp <= r.End; cp++ {
+			codepoint := getCodepoint(cp)
+			if codepoint.Category() != "Nd" {
+				continue
+			}
+			value := digitValue(cp)
+			table.Rows = append(table.Rows, MappingRow{
+				Codepoint: cp,
+				Values:    []interface{}{value, cp - rune(value), codepoint.Script},
+			})
+		}
+	}
+	return table
+}
+
+func runDigits(args []string) {
+	flags := flag.NewFlagSet("digits", flag.ExitOnError)
+	format := flags.String("format", "go", "Table format: go, c, or json")
+	pkg := flags.String("package", "main", "Package of the generated Go file")
+	name := flags.String("name", "Digits", "Name of the generated table")
+	flags.Usage = func() {
+		fmt.Println("Usage: unicode_range_finder digits [options] [search params]")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+	requireAttributes("digits", "gc", "sc")
+
+	table := digitTable(subcommandRanges(flags.Args()), *name)
+	if err := emitMappingTable(os.Stdout, *format, *pkg, table); err != nil {
+		panic(err)
+	}
+}
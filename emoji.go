@@ -0,0 +1,271 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+func init() {
+	subcommands["emoji"] = subcommand{
+		description: "Search CLDR emoji annotations by short name or keyword",
+		run:         runEmoji,
+	}
+}
+
+// EmojiAnnotation is the CLDR short name (e.g. "grinning face") and keywords
+// of an emoji, which can be a single codepoint or a sequence.
+type EmojiAnnotation struct {
+	Sequence  []rune
+	ShortName string
+	Keywords  []string
+}
+
+// emojiAnnotations is filled in by -cldr-annotations, keyed by the emoji.
+var emojiAnnotations = map[string]*EmojiAnnotation{}
+
+// loadCLDRAnnotations reads a CLDR annotations file (common/annotations/en.xml
+// or common/annotationsDerived/en.xml from the CLDR release).
+func loadCLDRAnnotations(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var document struct {
+		Annotations []struct {
+			CP    string `xml:"cp,attr"`
+			Type  string `xml:"type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"annotations>annotation"`
+	}
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("%v: %w", path, err)
+	}
+
+	for _, a := range document.Annotations {
+		annotation, ok := emojiAnnotations[a.CP]
+		if !ok {
+			annotation = &EmojiAnnotation{Sequence: []rune(a.CP)}
+			emojiAnnotations[a.CP] = annotation
+		}
+		if a.Type == "tts" {
+			annotation.ShortName = strings.TrimSpace(a.Value)
+			continue
+		}
+		for _, keyword := range strings.Split(a.Value, "|") {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				annotation.Keywords = append(annotation.Keywords, keyword)
+			}
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the short name or any keyword contains term,
+// ignoring case.
+func (_this *EmojiAnnotation) Matches(term string) bool {
+	term = strings.ToLower(term)
+	if strings.Contains(strings.ToLower(_this.ShortName), term) {
+		return true
+	}
+	for _, keyword := range _this.Keywords {
+		if strings.Contains(strings.ToLower(keyword), term) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchEmoji returns every annotated emoji matching term, in codepoint
+// order.
+func searchEmoji(term string) (matches []*EmojiAnnotation) {
+	for _, annotation := range emojiAnnotations {
+		if annotation.Matches(term) {
+			matches = append(matches, annotation)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i].Sequence, matches[j].Sequence
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return
+}
+
+// matchedEmojiSequences are the multi-codepoint emoji that emojiname= params
+// matched, which can't be part of a codepoint set, so they get written after
+// the result instead (see emitEmojiSequences).
+var matchedEmojiSequences = map[string]*EmojiAnnotation{}
+
+// emojiNameMatcher matches the single codepoint emoji whose annotations match
+// term, and records the sequences that match in matchedEmojiSequences.
+func emojiNameMatcher(term string) CodepointMatcher {
+	if len(emojiAnnotations) == 0 {
+		panic(fmt.Errorf("emojiname=%v: no CLDR annotations loaded (see -cldr-annotations)", term))
+	}
+	codepoints := map[rune]bool{}
+	for _, annotation := range searchEmoji(term) {
+		if len(annotation.Sequence) == 1 {
+			codepoints[annotation.Sequence[0]] = true
+		} else {
+			matchedEmojiSequences[string(annotation.Sequence)] = annotation
+		}
+	}
+	return func(cp Codepoint) bool {
+		return codepoints[cp.Codepoint]
+	}
+}
+
+// jsonEmojiSequence is how the JSON formats write a matched emoji sequence.
+type jsonEmojiSequence struct {
+	Sequence []rune `json:"sequence"`
+	Name     string `json:"name"`
+}
+
+// emojiSequenceResults returns the matched emoji sequences in codepoint
+// order.
+func emojiSequenceResults() (sequences []jsonEmojiSequence) {
+	var keys []string
+	for key := range matchedEmojiSequences {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		annotation := matchedEmojiSequences[key]
+		sequences = append(sequences, jsonEmojiSequence{Sequence: annotation.Sequence, Name: annotation.ShortName})
+	}
+	return
+}
+
+// emitEmojiSequences writes the matched emoji sequences after a result: as
+// one object per sequence in jsonl, or as a separate section in the other
+// formats (commented out in the ones that have comments). The json format has
+// them in its document instead.
+func emitEmojiSequences(w io.Writer, format string) error {
+	sequences := emojiSequenceResults()
+	if len(sequences) == 0 || format == "json" {
+		return nil
+	}
+	if format == "jsonl" {
+		for _, sequence := range sequences {
+			line, err := json.Marshal(sequence)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	comment := outputFormats[format].comment
+	if comment == "" {
+		comment = "%v"
+	}
+	lines := []string{"", fmt.Sprintf(comment, "Emoji sequences:")}
+	for _, sequence := range sequences {
+		lines = append(lines, fmt.Sprintf(comment, fmt.Sprintf("    %v (%v)", codepointList(sequence.Sequence), sequence.Name)))
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// emojiProperties are the query params for the emoji properties (UTS #51),
+// by their long and short names, with the binary properties they test.
+var emojiProperties = map[string]string{
+	"emoji":                 "Emoji",
+	"emoji_presentation":    "EPres",
+	"epres":                 "EPres",
+	"emoji_modifier":        "EMod",
+	"emod":                  "EMod",
+	"emoji_modifier_base":   "EBase",
+	"ebase":                 "EBase",
+	"emoji_component":       "EComp",
+	"ecomp":                 "EComp",
+	"extended_pictographic": "ExtPict",
+	"extpict":               "ExtPict",
+}
+
+// emojiPropertyMatcher matches the codepoints that have (emoji=yes) or don't
+// have (emoji=no) an emoji property.
+func emojiPropertyMatcher(name string, value string) (CodepointMatcher, bool) {
+	property, ok := emojiProperties[name]
+	if !ok {
+		return nil, false
+	}
+	var want bool
+	switch strings.ToLower(value) {
+	case "yes", "y", "true":
+		want = true
+	case "no", "n", "false":
+		want = false
+	default:
+		return nil, false
+	}
+	requireAttributes(name+"="+value, property)
+	index := binaryPropertyIndex(property)
+	return func(cp Codepoint) bool {
+		return cp.Properties.Has(index) == want
+	}, true
+}
+
+func codepointList(sequence []rune) string {
+	var codepoints []string
+	for _, cp := range sequence {
+		codepoints = append(codepoints, fmt.Sprintf("U+%04X", cp))
+	}
+	return strings.Join(codepoints, " ")
+}
+
+func runEmoji(args []string) {
+	flags := flag.NewFlagSet("emoji", flag.ExitOnError)
+	flags.Func("cldr-annotations", "CLDR annotations file to load, e.g. common/annotations/en.xml (can be repeated)", loadCLDRAnnotations)
+	flags.Usage = func() {
+		fmt.Println("Usage: unicode_range_finder emoji -cldr-annotations <file> [options] <search term>")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+	if flags.NArg() == 0 || len(emojiAnnotations) == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "EMOJI\tCODEPOINTS\tNAME\tKEYWORDS")
+	for _, annotation := range searchEmoji(strings.Join(flags.Args(), " ")) {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", string(annotation.Sequence), codepointList(annotation.Sequence),
+			annotation.ShortName, strings.Join(annotation.Keywords, ", "))
+	}
+	tw.Flush()
+}
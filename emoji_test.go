@@ -0,0 +1,119 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testCLDRAnnotations = `<?xml version="1.0" encoding="UTF-8" ?>
+<ldml>
+	<annotations>
+		<annotation cp="😀">face | grin | grinning face</annotation>
+		<annotation cp="😀" type="tts">grinning face</annotation>
+		<annotation cp="👍🏽">+1 | hand | thumb | up | medium skin tone</annotation>
+		<annotation cp="👍🏽" type="tts">thumbs up: medium skin tone</annotation>
+		<annotation cp="👍">+1 | hand | thumb | up</annotation>
+		<annotation cp="👍" type="tts">thumbs up</annotation>
+	</annotations>
+</ldml>
+`
+
+// loadTestAnnotations loads testCLDRAnnotations in place of any loaded
+// annotations and matched sequences, and puts them back when the test ends.
+func loadTestAnnotations(t *testing.T) {
+	annotations, sequences := emojiAnnotations, matchedEmojiSequences
+	t.Cleanup(func() {
+		emojiAnnotations, matchedEmojiSequences = annotations, sequences
+	})
+	emojiAnnotations = map[string]*EmojiAnnotation{}
+	matchedEmojiSequences = map[string]*EmojiAnnotation{}
+	if err := loadCLDRAnnotations(writeTestFile(t, t.TempDir(), "en.xml", testCLDRAnnotations)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchEmoji(t *testing.T) {
+	loadTestAnnotations(t)
+	for _, test := range []struct {
+		term string
+		want []string
+	}{
+		{"grin", []string{"grinning face"}},
+		{"THUMB", []string{"thumbs up", "thumbs up: medium skin tone"}},
+		{"skin", []string{"thumbs up: medium skin tone"}},
+		{"+1", []string{"thumbs up", "thumbs up: medium skin tone"}},
+		{"banana", nil},
+	} {
+		var got []string
+		for _, annotation := range searchEmoji(test.term) {
+			got = append(got, annotation.ShortName)
+		}
+		if strings.Join(got, "|") != strings.Join(test.want, "|") {
+			t.Errorf("searchEmoji(%q) = %q, want %q", test.term, got, test.want)
+		}
+	}
+}
+
+func TestEmojiNameMatcher(t *testing.T) {
+	loadTestAnnotations(t)
+	matcher := emojiNameMatcher("thumb")
+	if !matcher(Codepoint{Codepoint: 0x1F44D}) {
+		t.Error("thumb doesn't match U+1F44D")
+	}
+	if matcher(Codepoint{Codepoint: 0x1F600}) {
+		t.Error("thumb matches U+1F600")
+	}
+	if _, ok := matchedEmojiSequences["\U0001F44D\U0001F3FD"]; !ok || len(matchedEmojiSequences) != 1 {
+		t.Errorf("matched sequences = %v, want only U+1F44D U+1F3FD", matchedEmojiSequences)
+	}
+}
+
+func TestEmitEmojiSequences(t *testing.T) {
+	loadTestAnnotations(t)
+	emit := func(format string) string {
+		var out bytes.Buffer
+		if err := emitEmojiSequences(&out, format); err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		return out.String()
+	}
+	if got := emit("ebnf"); got != "" {
+		t.Errorf("ebnf without matched sequences = %q, want nothing", got)
+	}
+
+	emojiNameMatcher("skin")
+	for _, test := range []struct {
+		format string
+		want   string
+	}{
+		{"jsonl", `{"sequence":[128077,127997],"name":"thumbs up: medium skin tone"}` + "\n"},
+		{"ebnf", "\n/* Emoji sequences: */\n/*     U+1F44D U+1F3FD (thumbs up: medium skin tone) */\n"},
+		{"sql", "\n-- Emoji sequences:\n--     U+1F44D U+1F3FD (thumbs up: medium skin tone)\n"},
+		{"json", ""},
+	} {
+		if got := emit(test.format); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.format, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+var legacyEncodings = []struct {
+	name     string
+	encoding encoding.Encoding
+}{
+	{"gb18030", simplifiedchinese.GB18030},
+	{"shift-jis", japanese.ShiftJIS},
+	{"euc-kr", korean.EUCKR},
+}
+
+func init() {
+	for _, e := range legacyEncodings {
+		e := e
+		outputFormats[e.name] = outputFormat{
+			description: fmt.Sprintf("%v byte sequences (unmappable codepoints are reported on stderr)", e.encoding),
+			emit: func(w io.Writer, ranges Ranges, opts *OutputOptions) error {
+				sequences, unmappable := encodedSequences(e.encoding, ranges)
+				for _, seq := range sequences {
+					fmt.Fprintf(w, "%v%v\n", opts.Leadup, seq)
+				}
+				if len(unmappable) > 0 {
+					reportWarning("%v codepoints can't be encoded in %v: %v",
+						unmappable.CodepointCount(), e.encoding, unmappable)
+				}
+				return nil
+			},
+		}
+	}
+}
+
+// encodedSequences encodes every codepoint in ranges and merges the resulting
+// byte strings (in byte order) into byte sequences.
+func encodedSequences(enc encoding.Encoding, ranges Ranges) (sequences []ByteSequence, unmappable Ranges) {
+	encoder := enc.NewEncoder()
+	var encoded [][]byte
+	for _, r := range ranges {
+		for cp := r.Begin; cp <= r.End; cp++ {
+			// Lone surrogates can't be encoded in anything (string(cp) would
+			// give U+FFFD).
+			if cp >= surrogateBegin && cp <= surrogateEnd {
+				unmappable = unmappable.Add(cp)
+				continue
+			}
+			b, err := encoder.Bytes([]byte(string(cp)))
+			if err != nil {
+				unmappable = unmappable.Add(cp)
+				continue
+			}
+			encoded = append(encoded, b)
+		}
+	}
+
+	sort.Slice(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	})
+
+	for _, b := range encoded {
+		seq := make(ByteSequence, len(b))
+		for i, v := range b {
+			seq[i] = ByteRange{Begin: v, End: v}
+		}
+		sequences = append(sequences, seq)
+	}
+	return collapseByteSequences(sequences), unmappable
+}
@@ -0,0 +1,44 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestEncodedSequencesUnmappable(t *testing.T) {
+	for _, e := range legacyEncodings {
+		sequences, unmappable := encodedSequences(e.encoding, rangesOf("41 d800-d801 dfff"))
+		if len(sequences) != 1 || sequences[0].String() != "[41]" {
+			t.Errorf("%v: expected sequences [[41]] but got %v", e.name, sequences)
+		}
+		if got, expected := unmappable.String(), rangesOf("d800-d801 dfff").String(); got != expected {
+			t.Errorf("%v: expected unmappable %v but got %v", e.name, expected, got)
+		}
+	}
+
+	_, unmappable := encodedSequences(japanese.ShiftJIS, rangesOf("1f600"))
+	if unmappable.String() != rangesOf("1f600").String() {
+		t.Errorf("shift-jis: expected 1f600 to be unmappable but got %v", unmappable)
+	}
+}
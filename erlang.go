@@ -0,0 +1,89 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	outputFormats["erlang"] = outputFormat{
+		description: "Erlang module with an is_member/1 function (-style tuples or guards)",
+		emit:        emitErlang,
+		comment:     "%%%% %v",
+	}
+}
+
+func emitErlang(w io.Writer, ranges Ranges, opts *OutputOptions) error {
+	style, err := opts.styleOf("tuples", "guards")
+	if err != nil {
+		return err
+	}
+	module := opts.nameOr("char_set")
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%%%% Code generated by %v. DO NOT EDIT.\n\n", commandLine()))
+	sb.WriteString(fmt.Sprintf("-module(%v).\n", module))
+	switch style {
+	case "tuples":
+		sb.WriteString("-export([ranges/0, is_member/1]).\n\n")
+		sb.WriteString("-define(RANGES, {")
+		for i, r := range ranges {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(fmt.Sprintf("\n    {16#%X, 16#%X}", r.Begin, r.End))
+		}
+		sb.WriteString("\n}).\n\n")
+		sb.WriteString(fmt.Sprintf("%%%% ranges returns the %v matched codepoints as sorted {Lo, Hi} tuples.\n", ranges.CodepointCount()))
+		sb.WriteString("ranges() ->\n    tuple_to_list(?RANGES).\n\n")
+		sb.WriteString(`%% is_member reports whether codepoint C is in the set.
+is_member(C) when is_integer(C) ->
+    search(C, ?RANGES, 1, tuple_size(?RANGES)).
+
+search(_, _, Low, High) when Low > High ->
+    false;
+search(C, Ranges, Low, High) ->
+    Mid = (Low + High) div 2,
+    {Lo, Hi} = element(Mid, Ranges),
+    if
+        C < Lo -> search(C, Ranges, Low, Mid - 1);
+        C > Hi -> search(C, Ranges, Mid + 1, High);
+        true -> true
+    end.
+`)
+	case "guards":
+		sb.WriteString("-export([is_member/1]).\n\n")
+		sb.WriteString(fmt.Sprintf("%%%% is_member reports whether codepoint C is one of the %v matched codepoints.\n", ranges.CodepointCount()))
+		for _, r := range ranges {
+			if r.Begin == r.End {
+				sb.WriteString(fmt.Sprintf("is_member(16#%X) -> true;\n", r.Begin))
+			} else {
+				sb.WriteString(fmt.Sprintf("is_member(C) when is_integer(C), C >= 16#%X, C =< 16#%X -> true;\n", r.Begin, r.End))
+			}
+		}
+		sb.WriteString("is_member(_) -> false.\n")
+	}
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
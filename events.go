@@ -0,0 +1,140 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// When the tool is wrapped by a build system, errors and warnings need to be
+// machine readable. With -json-errors, a fatal error is written to stderr as a
+// single JSON object. With -events, everything (progress, warnings, the
+// result, and errors) is written to stdout as JSON Lines records, each with an
+// "event" field saying what kind of record it is.
+var (
+	jsonErrors  bool
+	eventStream io.Writer
+	// eventLock keeps records from queries running in parallel from being
+	// interleaved.
+	eventLock sync.Mutex
+	// warningOutput is where warnings go when they aren't events.
+	warningOutput io.Writer = os.Stderr
+)
+
+// addStructuredErrorFlags adds -json-errors and -events to flags. The tool
+// and all of its subcommands take them.
+func addStructuredErrorFlags(flags *flag.FlagSet) {
+	flags.BoolVar(&jsonErrors, "json-errors", false, "Report fatal errors as a JSON object on stderr")
+	flags.Var(eventsFlag{}, "events", "Write progress, warnings, the result, and errors to stdout as JSON Lines records")
+}
+
+// eventsFlag is a bool flag that turns the event stream on.
+type eventsFlag struct{}
+
+func (eventsFlag) IsBoolFlag() bool { return true }
+
+func (eventsFlag) String() string { return "false" }
+
+func (eventsFlag) Set(value string) error {
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	eventStream = nil
+	if on {
+		eventStream = os.Stdout
+	}
+	return nil
+}
+
+func emitEvent(event string, fields map[string]interface{}) {
+	record := map[string]interface{}{"event": event}
+	for k, v := range fields {
+		record[k] = v
+	}
+	eventLock.Lock()
+	defer eventLock.Unlock()
+	writeJSONLine(eventStream, record)
+}
+
+func writeJSONLine(w io.Writer, value interface{}) {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		panic(err)
+	}
+}
+
+func reportWarning(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if eventStream != nil {
+		emitEvent("warning", map[string]interface{}{"message": message})
+		return
+	}
+	fmt.Fprintln(warningOutput, message)
+}
+
+func reportProgress(plane int, lastPlane int) {
+	if eventStream != nil {
+		emitEvent("progress", map[string]interface{}{"plane": plane, "last_plane": lastPlane})
+	}
+}
+
+func reportResult(format string, ranges Ranges, output string) {
+	jsonRanges := make([][2]rune, 0, len(ranges))
+	for _, r := range ranges {
+		jsonRanges = append(jsonRanges, [2]rune{r.Begin, r.End})
+	}
+	emitEvent("result", map[string]interface{}{
+		"format":          format,
+		"codepoint_count": ranges.CodepointCount(),
+		"ranges":          jsonRanges,
+		"output":          output,
+	})
+}
+
+func structuredErrors() bool {
+	return jsonErrors || eventStream != nil
+}
+
+// handleFatalError turns a panic into an error record when structured errors
+// are enabled. It must be deferred.
+func handleFatalError() {
+	if !structuredErrors() {
+		return
+	}
+	if e := recover(); e != nil {
+		record := map[string]interface{}{"message": fmt.Sprint(e)}
+		if eventStream != nil {
+			emitEvent("error", record)
+		} else {
+			record["event"] = "error"
+			writeJSONLine(os.Stderr, record)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A query is a boolean expression over search params:
+//
+//	or    = and { ["|"] and }
+//	and   = unary { "&" unary }
+//	unary = ("!" | "not:") unary | "(" or ")" | param
+//
+// Params next to each other with only whitespace between them are OR'd, the
+// same as with "|", so a plain list of params means what it always has. A
+// negated operand in such a list is an exclusion instead: it gets taken away
+// from what the rest of the list matches, so "cat=L !ch=a-f" is the letters
+// other than a-f. Joining it with "|" (as in "cat=L | !ch=a-f") keeps it an
+// ordinary operand.
+//
+// A param's value always includes its first character, even if that's an
+// operator (e.g. ch=( or ch=|), and otherwise ends at whitespace or at one of
+// ) & |.
+
+const (
+	queryOperators = "()&|!"
+	notPrefix      = "not:"
+)
+
+type exprToken struct {
+	// operator is one of queryOperators, or 0 for a param.
+	operator byte
+	text     string
+	// offset is where the token starts in the query.
+	offset int
+}
+
+func tokenizeQuery(q string) (tokens []exprToken) {
+	for i := 0; i < len(q); {
+		r, size := utf8.DecodeRuneInString(q[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+		if strings.IndexByte(queryOperators, q[i]) >= 0 {
+			tokens = append(tokens, exprToken{operator: q[i], text: q[i : i+1], offset: i})
+			i++
+			continue
+		}
+		if strings.HasPrefix(q[i:], notPrefix) {
+			tokens = append(tokens, exprToken{operator: '!', text: notPrefix, offset: i})
+			i += len(notPrefix)
+			continue
+		}
+
+		start := i
+		valueStart := -1
+		for i < len(q) {
+			r, size := utf8.DecodeRuneInString(q[i:])
+			if i != valueStart && (unicode.IsSpace(r) || strings.ContainsRune(")&|", r)) {
+				break
+			}
+			if r == '=' && valueStart < 0 {
+				valueStart = i + size
+			}
+			i += size
+		}
+		tokens = append(tokens, exprToken{text: q[start:i], offset: start})
+	}
+	return
+}
+
+// isQueryExpression returns true if q uses any operators, rather than just
+// being a list of params.
+func isQueryExpression(q string) bool {
+	for _, token := range tokenizeQuery(q) {
+		if token.operator != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type queryParser struct {
+	query  string
+	tokens []exprToken
+	next   int
+}
+
+func newQueryParser(q string) *queryParser {
+	return &queryParser{query: q, tokens: tokenizeQuery(q)}
+}
+
+func (_this *queryParser) peek() (exprToken, bool) {
+	if _this.next >= len(_this.tokens) {
+		return exprToken{}, false
+	}
+	return _this.tokens[_this.next], true
+}
+
+func (_this *queryParser) fail(message string) {
+	offset := len(_this.query)
+	if token, ok := _this.peek(); ok {
+		offset = token.offset
+	}
+	panic(fmt.Errorf("%v at column %v of query [%v]", message, offset+1, _this.query))
+}
+
+// finish fails if anything is left over after the expression.
+func (_this *queryParser) finish() {
+	if token, ok := _this.peek(); ok {
+		_this.fail("unexpected " + token.text)
+	}
+}
+
+// startsOperand returns true if the next token can start another operand of
+// an implicit OR.
+func (_this *queryParser) startsOperand() bool {
+	token, ok := _this.peek()
+	return ok && (token.operator == 0 || token.operator == '(' || token.operator == '!')
+}
+
+// orOperand is one of the operands of an OR.
+type orOperand struct {
+	text    string
+	matcher CodepointMatcher
+	// negated is what the operand negates, if it's nothing but a negation.
+	negated CodepointMatcher
+	// piped is true if the operand is joined to a neighbor with "|".
+	piped bool
+}
+
+func (_this *orOperand) isExclusion() bool {
+	return _this.negated != nil && !_this.piped
+}
+
+func (_this *queryParser) parseOrOperands() (operands []*orOperand) {
+	piped := false
+	for {
+		token, ok := _this.peek()
+		if !ok {
+			_this.fail("expected a search param")
+		}
+		matcher, negated := _this.parseAnd()
+		last := _this.tokens[_this.next-1]
+		operand := &orOperand{
+			text:    _this.query[token.offset : last.offset+len(last.text)],
+			matcher: matcher,
+			negated: negated,
+			piped:   piped,
+		}
+		operands = append(operands, operand)
+
+		piped = false
+		if token, ok := _this.peek(); ok && token.operator == '|' {
+			_this.next++
+			operand.piped = true
+			piped = true
+		} else if !_this.startsOperand() {
+			return
+		}
+	}
+}
+
+func (_this *queryParser) parseOr() CodepointMatcher {
+	var included, excluded []CodepointMatcher
+	for _, operand := range _this.parseOrOperands() {
+		if operand.isExclusion() {
+			excluded = append(excluded, operand.negated)
+		} else {
+			included = append(included, operand.matcher)
+		}
+	}
+
+	var matcher CodepointMatcher
+	switch len(included) {
+	case 0:
+		// Only exclusions, so they're taken away from everything.
+		matcher = func(cp Codepoint) bool { return true }
+	case 1:
+		matcher = included[0]
+	default:
+		matcher = anyOf(included...)
+	}
+	if len(excluded) > 0 {
+		matcher = allOf(matcher, not(anyOf(excluded...)))
+	}
+	return matcher
+}
+
+// parseAnd also returns what the operand negates, if it's a lone negation.
+func (_this *queryParser) parseAnd() (matcher CodepointMatcher, negated CodepointMatcher) {
+	matchers := []CodepointMatcher{}
+	for {
+		unary, unaryNegated := _this.parseUnary()
+		matchers = append(matchers, unary)
+		negated = unaryNegated
+		token, ok := _this.peek()
+		if !ok || token.operator != '&' {
+			break
+		}
+		_this.next++
+	}
+	if len(matchers) == 1 {
+		return matchers[0], negated
+	}
+	return allOf(matchers...), nil
+}
+
+func (_this *queryParser) parseUnary() (matcher CodepointMatcher, negated CodepointMatcher) {
+	token, ok := _this.peek()
+	if !ok {
+		_this.fail("expected a search param")
+	}
+	switch token.operator {
+	case '!':
+		_this.next++
+		operand, _ := _this.parseUnary()
+		return not(operand), operand
+	case '(':
+		_this.next++
+		matcher := _this.parseOr()
+		if closing, ok := _this.peek(); !ok || closing.operator != ')' {
+			_this.fail("expected )")
+		}
+		_this.next++
+		return matcher, nil
+	case 0:
+		_this.next++
+		matchers := parseParams(token.text)
+		if len(matchers) == 0 {
+			// Already reported as an unknown query param.
+			return func(cp Codepoint) bool { return false }, nil
+		}
+		return anyOf(matchers...), nil
+	}
+	_this.fail(fmt.Sprintf("unexpected %v", token.text))
+	return nil, nil
+}
+
+// parseMatchers parses a query into matchers, any of which can match a
+// codepoint.
+func parseMatchers(q string) []CodepointMatcher {
+	if !isQueryExpression(q) {
+		return parseParams(q)
+	}
+	parser := newQueryParser(q)
+	matcher := parser.parseOr()
+	parser.finish()
+	return []CodepointMatcher{matcher}
+}
+
+// queryAlternatives splits a query into the parts that get OR'd together at
+// its top level, e.g. "cat=Lu (cat=Ll & ch=a-f)" into "cat=Lu" and
+// "(cat=Ll & ch=a-f)". Exclusions aren't alternatives, so they're left out.
+func queryAlternatives(q string) (alternatives []string) {
+	if !isQueryExpression(q) {
+		return strings.Fields(q)
+	}
+	parser := newQueryParser(q)
+	operands := parser.parseOrOperands()
+	parser.finish()
+	for _, operand := range operands {
+		if !operand.isExclusion() {
+			alternatives = append(alternatives, operand.text)
+		}
+	}
+	return
+}
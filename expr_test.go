@@ -0,0 +1,152 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"reflect"
+	"testing"
+)
+
+// matchedASCII returns the printable ASCII characters that q matches. The
+// queries only use ch= and cp=, so they don't depend on the codepoint table.
+func matchedASCII(q string) string {
+	matchers := parseMatchers(q)
+	var matched []rune
+	for cp := rune(0x20); cp < 0x7f; cp++ {
+		for _, matcher := range matchers {
+			if matcher(Codepoint{Codepoint: cp}) {
+				matched = append(matched, cp)
+				break
+			}
+		}
+	}
+	return string(matched)
+}
+
+func TestTokenizeQuery(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		expected []string
+	}{
+		{"", nil},
+		{"ch=a cat=L", []string{"ch=a", "cat=L"}},
+		{"(ch=a|ch=b)&cat=L", []string{"(", "ch=a", "|", "ch=b", ")", "&", "cat=L"}},
+		{"ch=( ch=) ch=| ch=&", []string{"ch=(", "ch=)", "ch=|", "ch=&"}},
+		{"(ch=))", []string{"(", "ch=)", ")"}},
+		{"ch=a-z)", []string{"ch=a-z", ")"}},
+		{"  cat=Lu\t|\ncat=Ll  ", []string{"cat=Lu", "|", "cat=Ll"}},
+	} {
+		var actual []string
+		for _, token := range tokenizeQuery(test.query) {
+			actual = append(actual, token.text)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("[%v]: expected %q but got %q", test.query, test.expected, actual)
+		}
+	}
+}
+
+func TestQueryExpressions(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		expected string
+	}{
+		{"ch=a-c", "abc"},
+		{"ch=a-c ch=x", "abcx"},
+		{"ch=a-c | ch=x", "abcx"},
+		{"ch=a-c & ch=x-z", ""},
+		{"ch=a-z & cp=78-7a", "xyz"},
+		{"ch=a-c | ch=x-z & cp=79", "abcy"},
+		{"(ch=a-c | ch=x-z) & cp=61-79", "abcxy"},
+		{"((ch=a-f)) & (ch=d-k & ch=a-e)", "de"},
+		{"ch=( | ch=)", "()"},
+		{"ch=&&ch=&", "&"},
+		{"ch=|", "|"},
+	} {
+		if actual := matchedASCII(test.query); actual != test.expected {
+			t.Errorf("[%v]: expected [%v] but got [%v]", test.query, test.expected, actual)
+		}
+	}
+}
+
+func TestQueryExpressionErrors(t *testing.T) {
+	for _, query := range []string{
+		"(ch=a",
+		"ch=a)",
+		"ch=a |",
+		"& ch=a",
+		"ch=a & & ch=b",
+		"()",
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("[%v]: expected a panic", query)
+				}
+			}()
+			parseMatchers(query)
+		}()
+	}
+}
+
+func TestQueryAlternatives(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		expected []string
+	}{
+		{"cat=Lu cat=Ll", []string{"cat=Lu", "cat=Ll"}},
+		{"cat=Lu (cat=Ll & ch=a-f)", []string{"cat=Lu", "(cat=Ll & ch=a-f)"}},
+		{"cat=Lu | cat=Ll & ch=a-f", []string{"cat=Lu", "cat=Ll & ch=a-f"}},
+	} {
+		if actual := queryAlternatives(test.query); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("[%v]: expected %q but got %q", test.query, test.expected, actual)
+		}
+	}
+}
+
+func TestQueryNegation(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		expected string
+	}{
+		// A lone negation in a list is an exclusion.
+		{"ch=a-f !ch=b-e", "af"},
+		{"ch=a-f not:ch=b-e", "af"},
+		{"ch=a-c ch=x-z !ch=b !ch=y", "acxz"},
+		{"!ch=b-e ch=a-f", "af"},
+		// Joined with |, it's an ordinary operand.
+		{"ch=a-c | !cp=21-7e", " abc"},
+		{"!cp=21-7e | ch=a-c", " abc"},
+		// Only exclusions take away from everything.
+		{"!cp=21-7e", " "},
+		{"not:(cp=21-7e)", " "},
+		{"!!ch=a", "a"},
+		{"!(ch=a-c | ch=x-z) & cp=61-7a", "defghijklmnopqrstuvw"},
+		{"ch=a-z & !ch=b-y", "az"},
+		// So is one in parentheses.
+		{"ch=a (!cp=21-7e)", " a"},
+		{"ch=! ch=a", "!a"},
+	} {
+		if actual := matchedASCII(test.query); actual != test.expected {
+			t.Errorf("[%v]: expected [%v] but got [%v]", test.query, test.expected, actual)
+		}
+	}
+}
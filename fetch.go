@@ -0,0 +1,210 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const ucdZipName = "ucd.all.flat.zip"
+const ucdXMLName = "ucd.all.flat.xml"
+
+// ucdZipURL is where unicode.org keeps the flat UCD XML for a version
+// ("latest" for the current one).
+func ucdZipURL(version string) string {
+	if version == "latest" {
+		return "https://www.unicode.org/Public/UCD/latest/ucdxml/" + ucdZipName
+	}
+	return fmt.Sprintf("https://www.unicode.org/Public/%v/ucdxml/%v", version, ucdZipName)
+}
+
+func ucdCacheDir(version string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "unicode_range_finder", "ucd", version), nil
+}
+
+// fetchUCD returns the path of the flat UCD XML zip for version, downloading
+// it into the cache first if it isn't there already. A cached zip is checked
+// against the SHA-256 recorded when it was downloaded. "latest" is always
+// downloaded again, since it changes with every release.
+func fetchUCD(version string) (string, error) {
+	dir, err := ucdCacheDir(version)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, ucdZipName)
+	if version != "latest" {
+		if err := verifyCachedFile(path); err == nil {
+			return path, nil
+		}
+	}
+
+	url := ucdZipURL(version)
+	fmt.Fprintf(os.Stderr, "Downloading %v\n", url)
+	response, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%v: %v", url, response.Status)
+	}
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("%v: %v", url, err)
+	}
+	if err := verifyUCDZip(data, version); err != nil {
+		return "", fmt.Errorf("%v: %v", url, err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomically(path, data); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	if err := writeFileAtomically(path+".sha256", []byte(hex.EncodeToString(sum[:])+"\n")); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// verifyCachedFile checks that the file at path still has the SHA-256 that
+// was recorded next to it.
+func verifyCachedFile(path string) error {
+	expected, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != strings.TrimSpace(string(expected)) {
+		return fmt.Errorf("%v: checksum mismatch", path)
+	}
+	return nil
+}
+
+// verifyUCDZip checks that data is a zip holding the flat UCD XML for
+// version, since unicode.org doesn't publish checksums to compare against.
+func verifyUCDZip(data []byte, version string) error {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	file, err := archive.Open(ucdXMLName)
+	if err != nil {
+		return fmt.Errorf("no %v in the archive", ucdXMLName)
+	}
+	defer file.Close()
+	found, err := ucdXMLVersion(file)
+	if err != nil {
+		return err
+	}
+	if version != "latest" && found != version {
+		return fmt.Errorf("expected Unicode %v, but got %v", version, found)
+	}
+	return nil
+}
+
+// ucdXMLVersion reads the version from the description at the start of a UCD
+// XML document, without reading the rest of it.
+func ucdXMLVersion(r io.Reader) (string, error) {
+	decoder := xml.NewDecoder(r)
+	inDescription := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("not a UCD XML document: %v", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "repertoire" {
+				return "", fmt.Errorf("not a UCD XML document: no description")
+			}
+			inDescription = t.Name.Local == "description"
+		case xml.CharData:
+			if inDescription {
+				return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(t)), "Unicode")), nil
+			}
+		}
+	}
+}
+
+// openUnicodeDB opens the flat UCD XML at path, which can also be the zip
+// that unicode.org distributes it in.
+func openUnicodeDB(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, 4)
+	n, _ := io.ReadFull(file, magic)
+	if !bytes.Equal(magic[:n], []byte("PK\x03\x04")) {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return file, nil
+	}
+	file.Close()
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := archive.Open(ucdXMLName)
+	if err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("%v: no %v in the archive", path, ucdXMLName)
+	}
+	return &zipEntryReader{ReadCloser: entry, archive: archive}, nil
+}
+
+// zipEntryReader closes the archive along with the entry.
+type zipEntryReader struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (_this *zipEntryReader) Close() error {
+	err := _this.ReadCloser.Close()
+	if archiveErr := _this.archive.Close(); err == nil {
+		err = archiveErr
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTestZip writes a zip archive holding files.
+func writeTestZip(t *testing.T, path string, files map[string]string) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+	for name, contents := range files {
+		entry, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadUnicodeDBFromZip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ucd.all.flat.zip")
+	writeTestZip(t, path, map[string]string{
+		"readme.txt": "Not the XML",
+		ucdXMLName:   testUCDXML,
+	})
+	version, loaded := loadTestCodepoints(t, path)
+	if version != "14.0.0" {
+		t.Errorf("expected version 14.0.0 but got %v", version)
+	}
+	if !reflect.DeepEqual(loaded, testUCDXMLCodepoints) {
+		t.Errorf("expected %v but got %v", testUCDXMLCodepoints, loaded)
+	}
+}
+
+func TestOpenUnicodeDB(t *testing.T) {
+	dir := t.TempDir()
+	withoutXML := filepath.Join(dir, "other.zip")
+	writeTestZip(t, withoutXML, map[string]string{"readme.txt": "Not the XML"})
+	if _, err := openUnicodeDB(withoutXML); err == nil || !strings.Contains(err.Error(), "no "+ucdXMLName) {
+		t.Errorf("expected an error about the missing XML but got %v", err)
+	}
+
+	truncated := writeTestFile(t, dir, "truncated.zip", "PK\x03\x04 and then nothing")
+	if _, err := openUnicodeDB(truncated); err == nil {
+		t.Errorf("expected an error for a truncated archive")
+	}
+
+	// Anything that isn't a zip archive is read as it is, even if it's
+	// shorter than the zip signature.
+	for name, contents := range map[string]string{"short.xml": "<u", "plain.xml": testUCDXML} {
+		file, err := openUnicodeDB(writeTestFile(t, dir, name, contents))
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		read, err := io.ReadAll(file)
+		file.Close()
+		if err != nil || string(read) != contents {
+			t.Errorf("%v: read [%s] (%v)", name, read, err)
+		}
+	}
+}
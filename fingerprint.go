@@ -0,0 +1,68 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Fingerprint identifies the set of codepoints that a query produced. Hash
+// only depends on the set itself, so it stays the same no matter how the
+// query was written or how the output was formatted, and changes whenever a
+// regenerated result would.
+type Fingerprint struct {
+	Query   string
+	Hash    string
+	Version string
+}
+
+func fingerprintOf(q string, ranges Ranges) Fingerprint {
+	hash := sha256.New()
+	// Merge any adjacent ranges (e.g. from -max-span) so that the same set
+	// always hashes the same.
+	for _, r := range unionRanges(ranges, nil) {
+		fmt.Fprintf(hash, "%X-%X\n", r.Begin, r.End)
+	}
+	return Fingerprint{
+		Query:   normalizeQuery(q),
+		Hash:    "sha256:" + hex.EncodeToString(hash.Sum(nil)),
+		Version: generatedUnicodeVersion,
+	}
+}
+
+func (_this Fingerprint) String() string {
+	return fmt.Sprintf("fingerprint %v of [%v] (Unicode %v)", _this.Hash, _this.Query, _this.Version)
+}
+
+// fingerprintComment returns the fingerprint as a comment in the given output
+// format.
+func fingerprintComment(format string, fingerprint Fingerprint) (string, error) {
+	f, ok := outputFormats[format]
+	if !ok {
+		return "", fmt.Errorf("%v: unknown output format", format)
+	}
+	if f.comment == "" {
+		return "", fmt.Errorf("%v: format has no comment syntax to hold a fingerprint", format)
+	}
+	return fmt.Sprintf(f.comment, fingerprint) + "\n", nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func init() {
+	subcommands["foldmap"] = subcommand{
+		description: "Generate case folding tables for the matched codepoints",
+		run:         runFoldmap,
+	}
+}
+
+// caseFoldingTable builds a table of every codepoint in ranges that folds to
+// something other than itself, following CaseFolding.txt. The simple folding
+// covers the C and S entries, and the full folding covers the C and F
+// entries.
+func caseFoldingTable(ranges Ranges, full bool, name string) *MappingTable {
+	table := &MappingTable{
+		Name:    name,
+		Doc:     "Simple case foldings (CaseFolding.txt status C and S).",
+		Columns: []MappingColumn{{Name: "Simple", Kind: ColumnRune}},
+	}
+	if full {
+		table.Doc = "Simple (status C and S) and full (status C and F) case foldings from CaseFolding.txt."
+		table.Columns = append(table.Columns, MappingColumn{Name: "Full", Kind: ColumnRunes})
+	}
+
+	for _, r := range ranges {
+		for cp := r.Begin; cp <= r.End; cp++ {
+			simple := codepointSequence("scf", cp)[0]
+			row := MappingRow{Codepoint: cp, Values: []interface{}{simple}}
+			changes := simple != cp
+			if full {
+				folded := codepointSequence("cf", cp)
+				if len(folded) != 1 || folded[0] != cp {
+					changes = true
+				}
+				row.Values = append(row.Values, folded)
+			}
+			if changes {
+				table.Rows = append(table.Rows, row)
+			}
+		}
+	}
+	return table
+}
+
+func runFoldmap(args []string) {
+	flags := flag.NewFlagSet("foldmap", flag.ExitOnError)
+	format := flags.String("format", "go", "Table format: go, c, or json")
+	full := flags.Bool("full", false, "Also include full case foldings, which can fold to more than one codepoint")
+	pkg := flags.String("package", "main", "Package of the generated Go file")
+	name := flags.String("name", "CaseFoldings", "Name of the generated table")
+	flags.Usage = func() {
+		fmt.Println("Usage: unicode_range_finder foldmap [options] [search params]")
+		fmt.Println("\nOptions:")
+		flags.PrintDefaults()
+	}
+	parseSubcommandFlags(flags, args)
+	if *full {
+		requireAttributes("foldmap", "scf", "cf")
+	} else {
+		requireAttributes("foldmap", "scf")
+	}
+
+	table := caseFoldingTable(subcommandRanges(flags.Args()), *full, *name)
+	if err := emitMappingTable(os.Stdout, *format, *pkg, table); err != nil {
+		panic(err)
+	}
+}
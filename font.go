@@ -0,0 +1,110 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// fontCoverage is the union of the codepoints that the fonts loaded with
+// -font have glyphs for.
+var fontCoverage Ranges
+
+var fontsLoaded = 0
+
+// loadFont reads the cmap of a TrueType or OpenType font (or of every font in
+// a collection) and adds the codepoints it maps to a glyph to fontCoverage.
+func loadFont(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	collection, err := sfnt.ParseCollection(data)
+	if err != nil {
+		return fmt.Errorf("%v: %w", path, err)
+	}
+	for i := 0; i < collection.NumFonts(); i++ {
+		font, err := collection.Font(i)
+		if err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+		coverage, err := cmapCoverage(font)
+		if err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+		fontCoverage = unionRanges(fontCoverage, coverage)
+	}
+	fontsLoaded++
+	return nil
+}
+
+// cmapCoverage returns the codepoints that font maps to a glyph other than
+// .notdef.
+func cmapCoverage(font *sfnt.Font) (ranges Ranges, err error) {
+	buffer := &sfnt.Buffer{}
+	for cp := rune(0); cp <= maxCodepoint; cp++ {
+		if cp == surrogateBegin {
+			cp = surrogateEnd
+			continue
+		}
+		glyph, err := font.GlyphIndex(buffer, cp)
+		if err != nil {
+			return nil, err
+		}
+		if glyph == 0 {
+			continue
+		}
+		if last := len(ranges) - 1; last >= 0 && ranges[last].End+1 == cp {
+			ranges[last].End = cp
+			continue
+		}
+		ranges = append(ranges, Range{Begin: cp, End: cp})
+	}
+	return
+}
+
+func fontSupports(cp rune) bool {
+	i := sort.Search(len(fontCoverage), func(i int) bool { return fontCoverage[i].End >= cp })
+	return i < len(fontCoverage) && fontCoverage[i].Begin <= cp
+}
+
+// fontMatcher matches the codepoints that the loaded fonts have glyphs for
+// (font=supported), or that none of them do (font=missing).
+func fontMatcher(value string) (CodepointMatcher, bool) {
+	if fontsLoaded == 0 {
+		panic(fmt.Errorf("font=%v: no fonts loaded (see -font)", value))
+	}
+	switch value {
+	case "supported":
+		return func(cp Codepoint) bool {
+			return fontSupports(cp.Codepoint)
+		}, true
+	case "missing":
+		return func(cp Codepoint) bool {
+			return !fontSupports(cp.Codepoint)
+		}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseCodepointList reads a list of codepoints, one per line, that another
+// tool produced. Each line is either a codepoint number (0041, U+0041, 0x41
+// and so on) or the character itself. A line holding a single character is
+// always taken as the character, so "7" is U+0037 rather than U+0007, and a
+// line holding just a space is U+0020.
+func parseCodepointList(reader io.Reader) (ranges Ranges, err error) {
+	var codepoints Ranges
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if utf8.RuneCountInString(text) == 1 {
+			cp, _ := utf8.DecodeRuneInString(text)
+			if cp == utf8.RuneError {
+				return nil, fmt.Errorf("line %v: invalid UTF-8", line)
+			}
+			codepoints = append(codepoints, Range{Begin: cp, End: cp})
+			continue
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		cp, err := parseCodepointNotation(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %w", line, err)
+		}
+		codepoints = append(codepoints, Range{Begin: cp, End: cp})
+	}
+	return normalizeRanges(codepoints), scanner.Err()
+}
+
+// loadCodepointList reads a codepoint list from a file, or from stdin if path
+// is "-".
+func loadCodepointList(path string) (Ranges, error) {
+	if path == "-" {
+		ranges, err := parseCodepointList(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("stdin: %w", err)
+		}
+		return ranges, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	ranges, err := parseCodepointList(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return ranges, nil
+}
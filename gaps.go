@@ -0,0 +1,43 @@
+// Copyright 2022 Karl Stenerud
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package urf
+
+// blockGaps returns the unassigned (Cn) codepoints in every block that ranges
+// touches. These are the holes that a future version of Unicode might fill.
+// Codepoints that aren't in any block don't count.
+func blockGaps(ranges Ranges) Ranges {
+	requireAttributes("-gaps", "blk")
+	blocks := map[string]bool{}
+	for _, r := range ranges {
+		for cp := r.Begin; cp <= r.End; cp++ {
+			if block := getCodepoint(cp).Block; block != "" && block != "NB" {
+				blocks[block] = true
+			}
+		}
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	return query(0, maxCodepoint, func(cp Codepoint) bool {
+		return cp.MajorCategory == 'C' && cp.MinorCategory == 'n' && blocks[cp.Block]
+	}, nil)
+}